@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config holds the global application configuration.
@@ -19,6 +21,20 @@ type Config struct {
 	ApiUrl1fichier string
 	// ApiUrlJellyfin is the url of jellyfin instance
 	ApiUrlJellyfin string
+	// ApiRateLimit1fichier is the number of 1fichier API calls per minute of an account (0 disables the limit)
+	ApiRateLimit1fichier int
+	// RetryMaxAttempts is the number of retries of a transient download failure (0 disables retries)
+	RetryMaxAttempts int
+	// RetryBaseDelay is the delay before the first retry, doubled on each following attempt
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the delay between two retries
+	RetryMaxDelay time.Duration
+	// SecretKey encrypts the secrets stored in the database (e.g. file passwords),
+	// a key is generated in DataPath when empty
+	SecretKey string
+	// AllowPrivateLinks lets direct links reach loopback, link-local and private network
+	// addresses, e.g. a NAS on the local network
+	AllowPrivateLinks bool
 }
 
 // Cfg is the global configuration instance, accessible throughout the application.
@@ -33,6 +49,16 @@ func Load() {
 		DataPath:       getEnv("APP_DATA_PATH", "./data"),
 		ApiUrl1fichier: getEnv("APP_API_URL_1FICHIER", "https://api.1fichier.com/v1"),
 		ApiUrlJellyfin: getEnv("APP_API_URL_JELLYFIN", "http://192.168.1.20:8096"),
+
+		ApiRateLimit1fichier: getEnvInt("APP_API_RATE_LIMIT_1FICHIER", 30),
+
+		RetryMaxAttempts: getEnvInt("APP_RETRY_MAX_ATTEMPTS", 5),
+		RetryBaseDelay:   getEnvDuration("APP_RETRY_BASE_DELAY", 5*time.Second),
+		RetryMaxDelay:    getEnvDuration("APP_RETRY_MAX_DELAY", 5*time.Minute),
+
+		SecretKey: getEnv("APP_SECRET_KEY", ""),
+
+		AllowPrivateLinks: getEnvBool("APP_ALLOW_PRIVATE_LINKS", false),
 	}
 }
 
@@ -49,3 +75,36 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvInt retrieves an integer environment variable value by key.
+// If the environment variable is not set or invalid, it returns the fallback value.
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// getEnvBool retrieves a boolean environment variable value (e.g. "true", "1") by key.
+// If the environment variable is not set or invalid, it returns the fallback value.
+func getEnvBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// getEnvDuration retrieves a duration environment variable value (e.g. "30s", "5m") by key.
+// If the environment variable is not set or invalid, it returns the fallback value.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
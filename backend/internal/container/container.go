@@ -1,43 +1,67 @@
 package container
 
 import (
+	"context"
+	"dlbackend/internal/config"
 	"dlbackend/internal/database"
 	"dlbackend/internal/handler"
 	"dlbackend/internal/repository"
 	"dlbackend/internal/service"
+	"dlbackend/pkg/client"
 	"dlbackend/pkg/sse"
+	"dlbackend/pkg/worker"
 )
 
 // Container holds all application dependencies for dependency injection.
 type Container struct {
-	DB              *database.Database
-	SSEManager      sse.Manager
-	DownloadHandler handler.DownloadHandler
-	SettingsHandler handler.SettingsHandler
-	FilesHandler    handler.FilesHandler
+	DB                   *database.Database
+	SSEManager           sse.Manager
+	DownloadManager      *worker.DownloadManager
+	DownloadHandler      handler.DownloadHandler
+	DownloadGroupHandler handler.DownloadGroupHandler
+	SettingsHandler      handler.SettingsHandler
+	FilesHandler         handler.FilesHandler
 }
 
 // New creates a Container with all dependencies wired up.
 func New(db *database.Database, sseManager sse.Manager) *Container {
 	// Repositories
 	downloadRepo := repository.NewDownloadRepository(db)
+	groupRepo := repository.NewDownloadGroupRepository(db)
 	settingsRepo := repository.NewSettingsRepository(db)
 
+	// Providers, reading the 1fichier API key from the settings on each call
+	oneFichierClient := client.NewDynamicOneFichierClient(config.Cfg.ApiUrl1fichier, func() (string, error) {
+		settings, err := settingsRepo.Get()
+		if err != nil {
+			return "", err
+		}
+		return settings.APIKey1fichier, nil
+	})
+	providers := client.NewProviders(oneFichierClient, config.Cfg.AllowPrivateLinks)
+
+	// Download queue
+	downloadManager := worker.NewDownloadManager(context.Background(), downloadRepo, settingsRepo, providers, sseManager)
+
 	// Services
 	filesService := service.NewFilesService()
-	downloadService := service.NewDownloadService(downloadRepo, settingsRepo, filesService, sseManager)
-	settingsService := service.NewSettingsService(settingsRepo)
+	downloadService := service.NewDownloadService(downloadRepo, groupRepo, settingsRepo, filesService, sseManager, downloadManager, providers, oneFichierClient)
+	groupService := service.NewDownloadGroupService(groupRepo, downloadRepo, downloadManager)
+	settingsService := service.NewSettingsService(settingsRepo, downloadManager)
 
 	// Handlers
 	downloadHandler := handler.NewDownloadHandler(downloadService)
+	groupHandler := handler.NewDownloadGroupHandler(groupService)
 	settingsHandler := handler.NewSettingsHandler(settingsService)
 	filesHandler := handler.NewFilesHandler(filesService)
 
 	return &Container{
-		DB:              db,
-		SSEManager:      sseManager,
-		DownloadHandler: downloadHandler,
-		SettingsHandler: settingsHandler,
-		FilesHandler:    filesHandler,
+		DB:                   db,
+		SSEManager:           sseManager,
+		DownloadManager:      downloadManager,
+		DownloadHandler:      downloadHandler,
+		DownloadGroupHandler: groupHandler,
+		SettingsHandler:      settingsHandler,
+		FilesHandler:         filesHandler,
 	}
 }
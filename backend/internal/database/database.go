@@ -26,7 +26,7 @@ func New() (*Database, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&model.Settings{}, &model.Download{})
+	err = db.AutoMigrate(&model.Settings{}, &model.Download{}, &model.DownloadGroup{})
 	if err != nil {
 		return nil, err
 	}
@@ -11,12 +11,19 @@ import (
 type AppError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	Details any    `json:"details,omitempty"` // Optional payload returned along with the message
 }
 
 func (e *AppError) Error() string {
 	return e.Message
 }
 
+// WithDetails attaches a payload to the error response.
+func (e *AppError) WithDetails(details any) *AppError {
+	e.Details = details
+	return e
+}
+
 // Helpers
 
 func BadRequest(msg string) *AppError {
@@ -43,6 +50,10 @@ func Forbidden(msg string) *AppError {
 	return &AppError{Code: fiber.StatusForbidden, Message: msg}
 }
 
+func TooManyRequests(msg string) *AppError {
+	return &AppError{Code: fiber.StatusTooManyRequests, Message: msg}
+}
+
 func Internal(msg string) *AppError {
 	return &AppError{Code: fiber.StatusInternalServerError, Message: msg}
 }
@@ -50,6 +61,9 @@ func Internal(msg string) *AppError {
 func HandleError(c fiber.Ctx, err error) error {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
+		if appErr.Details != nil {
+			return c.Status(appErr.Code).JSON(fiber.Map{"error": appErr.Message, "details": appErr.Details})
+		}
 		return c.Status(appErr.Code).JSON(fiber.Map{"error": appErr.Message})
 	}
 
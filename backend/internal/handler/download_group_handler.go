@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"dlbackend/internal/errors"
+	"dlbackend/internal/service"
+	"dlbackend/internal/utils"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// DownloadGroupHandler handles HTTP requests for download group operations.
+type DownloadGroupHandler interface {
+	ListGroups(c fiber.Ctx) error
+	PauseGroup(c fiber.Ctx) error
+	ResumeGroup(c fiber.Ctx) error
+	CancelGroup(c fiber.Ctx) error
+	ArchiveGroup(c fiber.Ctx) error
+}
+
+type downloadGroupHandler struct {
+	service service.DownloadGroupService
+}
+
+// NewDownloadGroupHandler creates a new DownloadGroupHandler instance.
+func NewDownloadGroupHandler(service service.DownloadGroupService) DownloadGroupHandler {
+	return &downloadGroupHandler{service: service}
+}
+
+// ListGroups get the download groups not archived with their aggregate progress
+func (h *downloadGroupHandler) ListGroups(c fiber.Ctx) error {
+	groups, err := h.service.ListGroups()
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(groups)
+}
+
+// PauseGroup pause all downloads of a group
+func (h *downloadGroupHandler) PauseGroup(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+
+	if err := h.service.PauseGroup(id); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to pause group: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ResumeGroup resume all downloads of a group
+func (h *downloadGroupHandler) ResumeGroup(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+
+	if err := h.service.ResumeGroup(id); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to resume group: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// CancelGroup cancel all downloads of a group
+func (h *downloadGroupHandler) CancelGroup(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+
+	if err := h.service.CancelGroup(id); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to cancel group: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ArchiveGroup archive all downloads of a group
+func (h *downloadGroupHandler) ArchiveGroup(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+
+	if err := h.service.ArchiveGroup(id); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to archive group: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -7,6 +7,7 @@ import (
 	"dlbackend/internal/utils"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -16,9 +17,15 @@ type DownloadHandler interface {
 	GetInfos(c fiber.Ctx) error
 	ListDownloads(c fiber.Ctx) error
 	CreateDownload(c fiber.Ctx) error
+	CreateDownloadBatch(c fiber.Ctx) error
 	PauseDownload(c fiber.Ctx) error
 	ResumeDownload(c fiber.Ctx) error
 	CancelDownload(c fiber.Ctx) error
+	SetSpeedLimit(c fiber.Ctx) error
+	SetPriority(c fiber.Ctx) error
+	MoveToTop(c fiber.Ctx) error
+	MoveToBottom(c fiber.Ctx) error
+	ReorderQueue(c fiber.Ctx) error
 	ArchiveDownload(c fiber.Ctx) error
 	DeleteDownload(c fiber.Ctx) error
 }
@@ -27,14 +34,14 @@ type downloadHandler struct {
 	service service.DownloadService
 }
 
-// GetInfos get file info from 1fichier api
+// GetInfos get file info from the provider of the link
 func (h *downloadHandler) GetInfos(c fiber.Ctx) error {
 	url, err := utils.ValidateNotEmpty("url", c.Query("url"))
 	if err != nil {
 		return errors.HandleError(c, err)
 	}
 
-	fileinfo, err := h.service.GetFileinfo(url)
+	fileinfo, err := h.service.GetFileinfo(c.Context(), url)
 	if err != nil {
 		return errors.HandleError(c, err)
 	}
@@ -51,6 +58,10 @@ func NewDownloadHandler(service service.DownloadService) DownloadHandler {
 func (h *downloadHandler) ListDownloads(c fiber.Ctx) error {
 	status := c.Query("status", "")
 	downloadType := c.Query("type", "")
+	sort, err := utils.ValidateDownloadSort(c.Query("sort", ""))
+	if err != nil {
+		return errors.HandleError(c, errors.BadRequest(err.Error()))
+	}
 	page := fiber.Query[int](c, "page", 1)
 	limit := fiber.Query[int](c, "limit", 20)
 
@@ -83,7 +94,7 @@ func (h *downloadHandler) ListDownloads(c fiber.Ctx) error {
 		}
 	}
 
-	downloads, total, err := h.service.ListDownloads(statusFilters, typeFilters, page, limit)
+	downloads, total, err := h.service.ListDownloads(statusFilters, typeFilters, sort, page, limit)
 	if err != nil {
 		return errors.HandleError(c,
 			fmt.Errorf("failed to list downloads: status=%v; typeFilters=%v; page=%d; limit=%d; error=%s", statusFilters, typeFilters, page, limit, err.Error()),
@@ -103,7 +114,7 @@ func (h *downloadHandler) ListDownloads(c fiber.Ctx) error {
 	})
 }
 
-// CreateDownload create and start download
+// CreateDownload create and queue download
 func (h *downloadHandler) CreateDownload(c fiber.Ctx) error {
 	// Validate request body
 	var req model.CreateDownloadRequest
@@ -111,7 +122,7 @@ func (h *downloadHandler) CreateDownload(c fiber.Ctx) error {
 		return errors.HandleBodyParserError(c, err)
 	}
 	// Validate URL
-	urlStr, err := utils.Validate1FichierURL(req.URL)
+	urlStr, err := utils.ValidateDownloadURL(req.URL)
 	if err != nil {
 		return errors.HandleError(c, errors.BadRequest(err.Error()))
 	}
@@ -125,20 +136,185 @@ func (h *downloadHandler) CreateDownload(c fiber.Ctx) error {
 	if err != nil {
 		return errors.HandleError(c, errors.BadRequest(err.Error()))
 	}
+	// Validate conflict policy
+	onConflict, err := validateConflictPolicy(req.OnConflict)
+	if err != nil {
+		return errors.HandleError(c, errors.BadRequest(err.Error()))
+	}
+	// Folder link: one download per file, grouped together
+	if utils.Is1FichierFolderURL(urlStr) {
+		if req.Password != nil && *req.Password != "" {
+			return errors.HandleError(c, errors.BadRequest("password is not supported for folder links"))
+		}
+		group, downloads, duplicates, err := h.service.CreateFolderDownloads(c.Context(), urlStr, downloadType, fileDir, onConflict)
+		if err != nil {
+			return errors.HandleError(c, err)
+		}
+		status := fiber.StatusCreated
+		if len(downloads) == 0 {
+			status = fiber.StatusOK // All files skipped
+		}
+		return c.Status(status).JSON(model.CreateFolderDownloadResponse{
+			Group:      group,
+			Downloads:  cloneDownloads(downloads),
+			Duplicates: duplicates,
+		})
+	}
 	// Validate fileName
 	fileName, err := utils.ValidateFileName(*req.FileName)
 	if err != nil {
 		return errors.HandleError(c, errors.BadRequest(err.Error()))
 	}
 
-	download, err := h.service.CreateDownload(urlStr, downloadType, fileDir, fileName)
+	password := ""
+	if req.Password != nil {
+		password = *req.Password
+	}
+
+	download, duplicates, err := h.service.CreateDownload(c.Context(), urlStr, downloadType, fileDir, fileName, nil, onConflict, password)
 	if err != nil {
 		return errors.HandleError(c, err)
 	}
+	if download == nil {
+		return c.Status(fiber.StatusOK).JSON(model.SkippedDownloadResponse{Skipped: true, Duplicates: duplicates})
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(download.Clone())
 }
 
+// validateConflictPolicy validates the optional conflict policy, nil for the settings default.
+func validateConflictPolicy(value *string) (*model.ConflictPolicy, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	policy, err := utils.ValidateConflictPolicy(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// maxBatchSize is the maximum number of URLs in a batch creation request.
+const maxBatchSize = 200
+
+// CreateDownloadBatch create and queue downloads from a list of URLs sharing a type and directory.
+// Each URL is created independently: the response reports success or failure per item.
+// The file links are grouped in a new download group, folder links get their own group.
+// The group of the file links is deleted when none of them was created.
+func (h *downloadHandler) CreateDownloadBatch(c fiber.Ctx) error {
+	// Validate request body
+	var req model.CreateDownloadBatchRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return errors.HandleBodyParserError(c, err)
+	}
+	if len(req.Items) == 0 {
+		return errors.HandleError(c, errors.BadRequest("'items' is required"))
+	}
+	if len(req.Items) > maxBatchSize {
+		return errors.HandleError(c, errors.BadRequest(fmt.Sprintf("too many items (max %d)", maxBatchSize)))
+	}
+	// Validate download type
+	downloadType, err := utils.ValidateType(req.Type)
+	if err != nil {
+		return errors.HandleError(c, errors.BadRequest(err.Error()))
+	}
+	// Validate dirName
+	fileDir := ""
+	if req.FileDir != nil {
+		if fileDir, err = utils.ValidateDirName(*req.FileDir); err != nil {
+			return errors.HandleError(c, errors.BadRequest(err.Error()))
+		}
+	}
+	// Validate conflict policy
+	onConflict, err := validateConflictPolicy(req.OnConflict)
+	if err != nil {
+		return errors.HandleError(c, errors.BadRequest(err.Error()))
+	}
+
+	// Validate group name
+	groupName := fmt.Sprintf("Batch %s", time.Now().Format("2006-01-02 15:04"))
+	if req.GroupName != nil && strings.TrimSpace(*req.GroupName) != "" {
+		groupName = strings.TrimSpace(*req.GroupName)
+	}
+	var group *model.DownloadGroup // Created with the first file link
+
+	results := make([]model.CreateDownloadBatchResult, len(req.Items))
+	seen := make(map[string]int, len(req.Items)) // file ID -> index of the first item
+	for i, item := range req.Items {
+		results[i].URL = item.URL
+
+		// Validate URL
+		urlStr, err := utils.ValidateDownloadURL(item.URL)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		// Skip duplicated links: 1fichier files by ID, the other links by URL
+		key := urlStr
+		if utils.Is1FichierFolderURL(urlStr) {
+			key = strings.ToLower(urlStr)
+		} else if utils.Is1FichierURL(urlStr) {
+			if key, err = utils.OneFichierFileID(urlStr); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+		}
+		if first, ok := seen[key]; ok {
+			results[i].Error = fmt.Sprintf("duplicate of item %d", first)
+			continue
+		}
+		seen[key] = i
+		// Folder link: one download per file, grouped together
+		if utils.Is1FichierFolderURL(urlStr) {
+			_, downloads, duplicates, err := h.service.CreateFolderDownloads(c.Context(), urlStr, downloadType, fileDir, onConflict)
+			results[i].Duplicates = duplicates
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Downloads = cloneDownloads(downloads)
+			results[i].Skipped = len(downloads) == 0
+			continue
+		}
+		// Validate fileName
+		fileName := ""
+		if item.FileName != nil {
+			if fileName, err = utils.ValidateFileName(*item.FileName); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+		}
+
+		if group == nil {
+			if group, err = h.service.CreateGroup(groupName, downloadType, fileDir); err != nil {
+				return errors.HandleError(c, err)
+			}
+		}
+
+		download, duplicates, err := h.service.CreateDownload(c.Context(), urlStr, downloadType, fileDir, fileName, &group.ID, onConflict, "")
+		results[i].Duplicates = duplicates
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if download == nil {
+			results[i].Skipped = true
+			continue
+		}
+		results[i].Download = download.Clone()
+	}
+
+	// No file link was created in the group: don't leave it empty in the group list
+	if group != nil && h.service.DeleteEmptyGroup(group.ID) {
+		group = nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"group":   group,
+		"results": results,
+	})
+}
+
 // PauseDownload pause a download
 func (h *downloadHandler) PauseDownload(c fiber.Ctx) error {
 	// Validate id param
@@ -148,7 +324,7 @@ func (h *downloadHandler) PauseDownload(c fiber.Ctx) error {
 	}
 
 	if err := h.service.PauseDownload(id); err != nil {
-		return errors.HandleError(c, fmt.Errorf("failed to pause download: %s %s", id, err.Error()))
+		return errors.HandleError(c, fmt.Errorf("failed to pause download: %s %w", id, err))
 	}
 
 	return c.SendStatus(fiber.StatusOK)
@@ -163,7 +339,7 @@ func (h *downloadHandler) ResumeDownload(c fiber.Ctx) error {
 	}
 
 	if err := h.service.ResumeDownload(id); err != nil {
-		return errors.HandleError(c, fmt.Errorf("failed to resume download: %s %s", id, err.Error()))
+		return errors.HandleError(c, fmt.Errorf("failed to resume download: %s %w", id, err))
 	}
 
 	return c.SendStatus(fiber.StatusOK)
@@ -178,7 +354,101 @@ func (h *downloadHandler) CancelDownload(c fiber.Ctx) error {
 	}
 
 	if err := h.service.CancelDownload(id); err != nil {
-		return errors.HandleError(c, fmt.Errorf("failed to cancel download: %s %s", id, err.Error()))
+		return errors.HandleError(c, fmt.Errorf("failed to cancel download: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// SetSpeedLimit change the bandwidth limit of a download
+func (h *downloadHandler) SetSpeedLimit(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+	// Validate request body
+	var req model.UpdateSpeedLimitRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return errors.HandleBodyParserError(c, err)
+	}
+	if req.SpeedLimit != nil && *req.SpeedLimit < 0 {
+		return errors.HandleError(c, errors.BadRequest("'speedLimit' must be positive or zero"))
+	}
+
+	if err := h.service.SetSpeedLimit(id, req.SpeedLimit); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to set speed limit: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// SetPriority change the queue priority of a download
+func (h *downloadHandler) SetPriority(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+	// Validate request body
+	var req model.UpdatePriorityRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return errors.HandleBodyParserError(c, err)
+	}
+	if req.Priority == nil {
+		return errors.HandleError(c, errors.BadRequest("'priority' is required"))
+	}
+
+	if err := h.service.SetPriority(id, *req.Priority); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to set priority: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// MoveToTop move a pending download to the top of the queue
+func (h *downloadHandler) MoveToTop(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+
+	if err := h.service.MoveToTop(id); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to move download to top: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// MoveToBottom move a pending download to the bottom of the queue
+func (h *downloadHandler) MoveToBottom(c fiber.Ctx) error {
+	// Validate id param
+	id, err := utils.ValidateNotEmpty("id", c.Params("id"))
+	if err != nil {
+		return errors.HandleError(c, err)
+	}
+
+	if err := h.service.MoveToBottom(id); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to move download to bottom: %s %w", id, err))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ReorderQueue reorder the pending downloads
+func (h *downloadHandler) ReorderQueue(c fiber.Ctx) error {
+	// Validate request body
+	var req model.ReorderQueueRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return errors.HandleBodyParserError(c, err)
+	}
+	if len(req.IDs) == 0 {
+		return errors.HandleError(c, errors.BadRequest("'ids' is required"))
+	}
+
+	if err := h.service.ReorderQueue(req.IDs); err != nil {
+		return errors.HandleError(c, fmt.Errorf("failed to reorder queue: %w", err))
 	}
 
 	return c.SendStatus(fiber.StatusOK)
@@ -193,7 +463,7 @@ func (h *downloadHandler) ArchiveDownload(c fiber.Ctx) error {
 	}
 
 	if err := h.service.ArchiveDownload(id); err != nil {
-		return errors.HandleError(c, fmt.Errorf("failed to archive download: %s %s", id, err.Error()))
+		return errors.HandleError(c, fmt.Errorf("failed to archive download: %s %w", id, err))
 	}
 
 	return c.SendStatus(fiber.StatusOK)
@@ -208,8 +478,17 @@ func (h *downloadHandler) DeleteDownload(c fiber.Ctx) error {
 	}
 
 	if err := h.service.DeleteDownload(id); err != nil {
-		return errors.HandleError(c, fmt.Errorf("failed to delete download: %s %s", id, err.Error()))
+		return errors.HandleError(c, fmt.Errorf("failed to delete download: %s %w", id, err))
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+// cloneDownloads copies downloads before serialization
+func cloneDownloads(downloads []*model.Download) []*model.Download {
+	clones := make([]*model.Download, len(downloads))
+	for i, download := range downloads {
+		clones[i] = download.Clone()
+	}
+	return clones
+}
@@ -4,6 +4,7 @@ import (
 	"dlbackend/internal/errors"
 	"dlbackend/internal/model"
 	"dlbackend/internal/service"
+	"dlbackend/internal/utils"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -12,6 +13,7 @@ import (
 type SettingsHandler interface {
 	GetSettings(c fiber.Ctx) error
 	UpdateSettings(c fiber.Ctx) error
+	GetSchedule(c fiber.Ctx) error
 }
 
 type settingsHandler struct {
@@ -40,6 +42,39 @@ func (h *settingsHandler) UpdateSettings(c fiber.Ctx) error {
 	if err := c.Bind().Body(&settings); err != nil {
 		return errors.HandleBodyParserError(c, err)
 	}
+	// Validate concurrency limit (0 keeps the current value)
+	if settings.MaxConcurrentDownloads != 0 {
+		if _, err := utils.ValidateIntRange("maxConcurrentDownloads", settings.MaxConcurrentDownloads, 1, 10); err != nil {
+			return errors.HandleError(c, errors.BadRequest(err.Error()))
+		}
+	}
+	// Validate segments per download (0 keeps the current value)
+	if settings.SegmentsPerDownload != 0 {
+		if _, err := utils.ValidateIntRange("segmentsPerDownload", settings.SegmentsPerDownload, 1, 16); err != nil {
+			return errors.HandleError(c, errors.BadRequest(err.Error()))
+		}
+	}
+	// Validate global speed limit (0 = unlimited)
+	if settings.SpeedLimit != nil && *settings.SpeedLimit < 0 {
+		return errors.HandleError(c, errors.BadRequest("'speedLimit' must be positive or zero"))
+	}
+	// Validate disk reserve (0 = none)
+	if settings.DiskReserve != nil && *settings.DiskReserve < 0 {
+		return errors.HandleError(c, errors.BadRequest("'diskReserve' must be positive or zero"))
+	}
+	// Validate schedule (nil keeps the current value)
+	if _, err := utils.ValidateSchedule(settings.Schedule); err != nil {
+		return errors.HandleError(c, errors.BadRequest(err.Error()))
+	}
+
+	// Validate conflict policy (empty keeps the current value)
+	if settings.ConflictPolicy != "" {
+		policy, err := utils.ValidateConflictPolicy(string(settings.ConflictPolicy))
+		if err != nil {
+			return errors.HandleError(c, errors.BadRequest(err.Error()))
+		}
+		settings.ConflictPolicy = policy
+	}
 
 	updated, err := h.service.UpdateSettings(&settings)
 	if err != nil {
@@ -48,3 +83,8 @@ func (h *settingsHandler) UpdateSettings(c fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(updated)
 }
+
+// GetSchedule get the download schedule state currently applied
+func (h *settingsHandler) GetSchedule(c fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(h.service.GetScheduleState())
+}
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// DownloadGroup relates downloads created together: the files of a 1fichier
+// folder link, or the links of a batch.
+type DownloadGroup struct {
+	ID        string       `gorm:"primaryKey" json:"id"`
+	Name      string       `json:"name"`
+	Type      DownloadType `json:"type"`
+	FileDir   string       `json:"fileDir"`   // Target directory, relative to the type directory
+	FolderURL *string      `json:"folderUrl"` // Expanded 1fichier folder link, nil for batches
+
+	// Aggregate progress of the downloads, computed on read
+	Progress DownloadGroupProgress `gorm:"-" json:"progress"`
+
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	IsArchived bool      `gorm:"default:false" json:"isArchived"`
+}
+
+// DownloadGroupProgress is the aggregate progress of the downloads of a group.
+type DownloadGroupProgress struct {
+	Total           int     `json:"total"`     // Number of downloads
+	Active          int     `json:"active"`    // Not finished yet
+	Completed       int     `json:"completed"` // Completed successfully
+	Failed          int     `json:"failed"`    // Failed, corrupted or cancelled
+	FileSize        int64   `json:"fileSize"`  // Sum of the known file sizes
+	DownloadedBytes int64   `json:"downloadedBytes"`
+	Progress        float64 `json:"progress"`
+	Speed           float64 `json:"speed"` // Sum of the active download speeds
+}
+
+// NewDownloadGroupProgress aggregates the progress of the downloads of a group.
+func NewDownloadGroupProgress(downloads []Download) DownloadGroupProgress {
+	var p DownloadGroupProgress
+	for _, d := range downloads {
+		p.Total++
+		switch {
+		case d.Status == StatusCompleted:
+			p.Completed++
+		case d.Status.IsFinished():
+			p.Failed++
+		default:
+			p.Active++
+			if d.Speed != nil {
+				p.Speed += *d.Speed
+			}
+		}
+		if d.FileSize != nil {
+			p.FileSize += *d.FileSize
+		}
+		p.DownloadedBytes += d.DownloadedBytes
+	}
+	if p.FileSize > 0 {
+		p.Progress = min(float64(p.DownloadedBytes)/float64(p.FileSize)*100, 100)
+	}
+	return p
+}
+
+type DownloadGroupProgressEvent struct {
+	GroupID string `json:"groupId"`
+	DownloadGroupProgress
+}
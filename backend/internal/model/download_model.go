@@ -3,7 +3,9 @@ package model
 import (
 	"dlbackend/internal/config"
 	"dlbackend/pkg/client"
+	"math"
 	"path/filepath"
+	"slices"
 	"time"
 )
 
@@ -15,12 +17,24 @@ const (
 	StatusRequestingInfos DownloadStatus = "REQUESTING_INFOS"
 	StatusRequestingToken DownloadStatus = "REQUESTING_TOKEN"
 	StatusDownloading     DownloadStatus = "DOWNLOADING"
+	StatusVerifying       DownloadStatus = "VERIFYING"
 	StatusPaused          DownloadStatus = "PAUSED"
 	StatusCancelled       DownloadStatus = "CANCELLED"
 	StatusFailed          DownloadStatus = "FAILED"
 	StatusCompleted       DownloadStatus = "COMPLETED"
+	StatusCorrupted       DownloadStatus = "CORRUPTED"
 )
 
+// IsFinished reports whether the download reached a final status.
+func (s DownloadStatus) IsFinished() bool {
+	switch s {
+	case StatusCompleted, StatusFailed, StatusCorrupted, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
 type DownloadType string
 
 const (
@@ -40,24 +54,78 @@ func (s DownloadType) Dir() string {
 	return "Inconnu"
 }
 
+// ConflictPolicy tells what to do when a download duplicates an existing one, or
+// when its final file already exists. It's evaluated at creation and again when
+// the download completes.
+type ConflictPolicy string
+
+const (
+	ConflictRename    ConflictPolicy = "RENAME"    // Keep both, with a numbered suffix on the new file name
+	ConflictOverwrite ConflictPolicy = "OVERWRITE" // Replace the existing file
+	ConflictSkip      ConflictPolicy = "SKIP"      // Do not create the download, or keep the existing file
+	ConflictFail      ConflictPolicy = "FAIL"      // Reject the download, or fail it keeping the temp file
+)
+
+// ConflictOutcome records how a conflict was resolved for a download.
+type ConflictOutcome string
+
+const (
+	OutcomeRenamed     ConflictOutcome = "RENAMED"
+	OutcomeOverwritten ConflictOutcome = "OVERWRITTEN"
+	OutcomeSkipped     ConflictOutcome = "SKIPPED"
+	OutcomeFailed      ConflictOutcome = "FAILED"
+)
+
+// DuplicateReason tells why a download duplicates an existing one.
+type DuplicateReason string
+
+const (
+	DuplicateURL      DuplicateReason = "URL"      // Same link already active or completed
+	DuplicateChecksum DuplicateReason = "CHECKSUM" // Same file already downloaded
+	DuplicateFile     DuplicateReason = "FILE"     // Final path already on disk, or used by another download
+)
+
+// DownloadDuplicate describes a conflict found before creating a download.
+type DownloadDuplicate struct {
+	Reason     DuplicateReason `json:"reason"`
+	URL        string          `json:"url"`        // Link of the download being created
+	DownloadID *string         `json:"downloadId"` // Conflicting download, nil for a file on disk only
+	Status     *DownloadStatus `json:"status"`
+	Path       string          `json:"path,omitempty"` // Conflicting final path (FILE only)
+}
+
+// DownloadSort is the order of the download list.
+type DownloadSort string
+
+const (
+	SortCreatedAt DownloadSort = "createdAt" // Most recent first
+	SortPriority  DownloadSort = "priority"  // Queue order: highest priority first, then oldest first
+)
+
 type Download struct {
 	ID string `gorm:"primaryKey" json:"id"`
 
 	// User input
-	FileURL        string       `json:"fileUrl"`
-	CustomFileDir  *string      `json:"customFileDir"`
-	CustomFileName *string      `json:"customFileName"`
-	Type           DownloadType `json:"type"`
+	FileURL        string          `json:"fileUrl"`
+	CustomFileDir  *string         `json:"customFileDir"`
+	CustomFileName *string         `json:"customFileName"`
+	Type           DownloadType    `json:"type"`
+	SpeedLimit     *int64          `json:"speedLimit"`                       // Bytes per second, nil = unlimited
+	GroupID        *string         `gorm:"index" json:"groupId"`             // DownloadGroup, nil for a single download
+	OnConflict     *ConflictPolicy `json:"onConflict"`                       // nil = Settings.ConflictPolicy
+	Priority       int             `gorm:"default:0;index" json:"priority"`  // Higher starts first among pending downloads
+	Password       *string         `json:"-"`                                // Encrypted (utils.EncryptSecret), nil if the file is not protected
+	Provider       string          `gorm:"default:1fichier" json:"provider"` // Name of the client.Provider handling the link
 
-	// Download infos (from 1fichier.com API)
+	// Download infos (from the provider)
 	FileName string  `json:"fileName"`
 	FileSize *int64  `json:"fileSize"`
 	MimeType *string `json:"mimeType"`
 	Checksum *string `json:"checksum"`
 
-	// Download token (from 1fichier.com API)
+	// Download token (from the provider)
 	DownloadURL          *string    `json:"DownloadURL"`
-	DownloadURLExpiresAt *time.Time `json:"downloadURLExpiresAt"` // Valid for 5 minutes only
+	DownloadURLExpiresAt *time.Time `json:"downloadURLExpiresAt"` // nil if it doesn't expire, 5 minutes for 1fichier
 
 	// Status Management
 	Status       DownloadStatus `json:"status"`
@@ -65,11 +133,17 @@ type Download struct {
 	StartedAt    *time.Time     `json:"startedAt"`   // Init only on first StatusDownloading
 	CompletedAt  *time.Time     `json:"completedAt"` // Init status StatusCompleted or StatusFail or Status
 
+	// Conflict Management
+	ConflictOutcome *ConflictOutcome `json:"conflictOutcome"` // nil if there was no conflict
+
 	// Progress Management
-	Progress        float64  `json:"progress"`
-	DownloadedBytes int64    `json:"downloadedBytes"`
-	Speed           *float64 `json:"speed"`
-	RetryCount      int      `json:"retryCount"`
+	Progress        float64           `json:"progress"`
+	DownloadedBytes int64             `json:"downloadedBytes"`
+	Speed           *float64          `json:"speed"`        // Moving average, bytes per second
+	ETA             *int64            `json:"eta"`          // Seconds remaining, nil if unknown
+	AverageSpeed    *float64          `json:"averageSpeed"` // Bytes per second since StartedAt, set on completion
+	RetryCount      int               `json:"retryCount"`
+	Segments        []DownloadSegment `gorm:"serializer:json" json:"segments,omitempty"` // Only for multi-connection downloads
 
 	// Others
 	CreatedAt  time.Time `json:"createdAt"`
@@ -77,8 +151,55 @@ type Download struct {
 	IsArchived bool      `gorm:"default:false" json:"isArchived"`
 }
 
+// DownloadSegment is a byte range of a multi-connection download.
+type DownloadSegment struct {
+	Start      int64 `json:"start"`
+	End        int64 `json:"end"` // Inclusive
+	Downloaded int64 `json:"downloaded"`
+}
+
+// Offset returns the absolute file offset of the next byte to download.
+func (s DownloadSegment) Offset() int64 {
+	return s.Start + s.Downloaded
+}
+
+// Done reports whether the whole byte range is downloaded.
+func (s DownloadSegment) Done() bool {
+	return s.Offset() > s.End
+}
+
+// ResolveConflictPolicy returns the conflict policy of the download, or the default one.
+func (d *Download) ResolveConflictPolicy(defaultPolicy ConflictPolicy) ConflictPolicy {
+	if d.OnConflict != nil && *d.OnConflict != "" {
+		return *d.OnConflict
+	}
+	return defaultPolicy
+}
+
+// RemainingTime returns the seconds left to download the file at the current speed,
+// nil if the size or the speed is unknown.
+func (d *Download) RemainingTime() *int64 {
+	if d.FileSize == nil || d.Speed == nil || *d.Speed <= 0 {
+		return nil
+	}
+	remaining := max(*d.FileSize-d.DownloadedBytes, 0)
+	eta := int64(math.Ceil(float64(remaining) / *d.Speed))
+	return &eta
+}
+
+// OwnsFinalFile reports whether the final file was written by this download: completed,
+// and not skipped in favor of a file already there (possibly another download's).
+func (d *Download) OwnsFinalFile() bool {
+	return d.Status == StatusCompleted && (d.ConflictOutcome == nil || *d.ConflictOutcome != OutcomeSkipped)
+}
+
+// HasFileName reports whether the file name is known (custom or from the provider).
+func (d *Download) HasFileName() bool {
+	return (d.CustomFileName != nil && *d.CustomFileName != "") || d.FileName != ""
+}
+
 func (d *Download) resolveFileName() string {
-	if d.CustomFileName != nil {
+	if d.CustomFileName != nil && *d.CustomFileName != "" {
 		return filepath.Base(*d.CustomFileName)
 	}
 	return filepath.Base(d.FileName)
@@ -104,6 +225,16 @@ func (d *Download) TempFilePath() (string, error) {
 	return filepath.Join(fileDir, fileName), nil
 }
 
+// CheckpointFilePath resolve the full path of the checkpoint sidecar of the temp file
+func (d *Download) CheckpointFilePath() (string, error) {
+	tempPath, err := d.TempFilePath()
+	if err != nil {
+		return "", err
+	}
+
+	return tempPath + ".state", nil
+}
+
 // FinalFilePath resolve the full final file path
 func (d *Download) FinalFilePath() (string, error) {
 	fileName := d.resolveFileName()
@@ -117,14 +248,83 @@ func (d *Download) FinalFilePath() (string, error) {
 
 func (d *Download) Clone() *Download {
 	cp := *d
+	cp.Segments = slices.Clone(d.Segments)
 	return &cp
 }
 
+// ProgressEvent builds the SSE progress event for the current download state.
+func (d *Download) ProgressEvent() DownloadProgressEvent {
+	return DownloadProgressEvent{
+		DownloadID:      d.ID,
+		FileName:        d.FileName,
+		CustomFileDir:   d.CustomFileDir,
+		CustomFileName:  d.CustomFileName,
+		Status:          string(d.Status),
+		Progress:        d.Progress,
+		DownloadedBytes: d.DownloadedBytes,
+		FileSize:        d.FileSize,
+		Speed:           d.Speed,
+		ETA:             d.ETA,
+		AverageSpeed:    d.AverageSpeed,
+		GroupID:         d.GroupID,
+	}
+}
+
 type CreateDownloadRequest struct {
-	Type     string  `json:"type"`
+	Type       string  `json:"type"`
+	URL        string  `json:"url"`
+	FileName   *string `json:"fileName"`
+	FileDir    *string `json:"fileDir"`
+	OnConflict *string `json:"onConflict"` // RENAME, OVERWRITE, SKIP or FAIL, defaults to the settings
+	Password   *string `json:"password"`   // Password of a protected file
+}
+
+type CreateDownloadBatchRequest struct {
+	Type       string                    `json:"type"`
+	FileDir    *string                   `json:"fileDir"`    // Shared by all items
+	GroupName  *string                   `json:"groupName"`  // Name of the download group, defaults to the date
+	OnConflict *string                   `json:"onConflict"` // Applied to all items
+	Items      []CreateDownloadBatchItem `json:"items"`
+}
+
+type CreateDownloadBatchItem struct {
 	URL      string  `json:"url"`
-	FileName *string `json:"fileName"`
-	FileDir  *string `json:"fileDir"`
+	FileName *string `json:"fileName"` // Optional, defaults to the file name of the provider
+}
+
+// CreateDownloadBatchResult is the outcome of one batch item: either Download, Skipped or Error is set.
+type CreateDownloadBatchResult struct {
+	URL        string              `json:"url"`
+	Download   *Download           `json:"download,omitempty"`
+	Downloads  []*Download         `json:"downloads,omitempty"` // Folder links only
+	Skipped    bool                `json:"skipped,omitempty"`
+	Duplicates []DownloadDuplicate `json:"duplicates,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// CreateFolderDownloadResponse lists the downloads expanded from a folder link.
+type CreateFolderDownloadResponse struct {
+	Group      *DownloadGroup      `json:"group"`
+	Downloads  []*Download         `json:"downloads"`
+	Duplicates []DownloadDuplicate `json:"duplicates,omitempty"`
+}
+
+// SkippedDownloadResponse is returned when a duplicated download is not created (SKIP).
+type SkippedDownloadResponse struct {
+	Skipped    bool                `json:"skipped"`
+	Duplicates []DownloadDuplicate `json:"duplicates"`
+}
+
+type UpdatePriorityRequest struct {
+	Priority *int `json:"priority"`
+}
+
+type ReorderQueueRequest struct {
+	IDs []string `json:"ids"` // Pending downloads in the new order, the others follow
+}
+
+type UpdateSpeedLimitRequest struct {
+	SpeedLimit *int64 `json:"speedLimit"` // Bytes per second, null or 0 = unlimited
 }
 
 type DownloadProgressEvent struct {
@@ -137,9 +337,30 @@ type DownloadProgressEvent struct {
 	DownloadedBytes int64    `json:"downloadedBytes"`
 	FileSize        *int64   `json:"fileSize"`
 	Speed           *float64 `json:"speed"`
+	ETA             *int64   `json:"eta"`
+	AverageSpeed    *float64 `json:"averageSpeed"`
+	GroupID         *string  `json:"groupId"`
+}
+
+type DownloadRetryEvent struct {
+	DownloadID  string `json:"downloadId"`
+	Attempt     int    `json:"attempt"`
+	MaxAttempts int    `json:"maxAttempts"`
+	DelayMs     int64  `json:"delayMs"`
+	Error       string `json:"error"`
+}
+
+// DiskLowEvent is sent when downloads are paused because the disk is almost full.
+type DiskLowEvent struct {
+	FreeSpace int64    `json:"freeSpace"` // Bytes
+	Reserve   int64    `json:"reserve"`   // Bytes, Settings.DiskReserve
+	PausedIDs []string `json:"pausedIds"`
 }
 
+// DownloadInfoResponse is the file info of a link. Fileinfo has the 1fichier API format,
+// only the common fields are set for the other providers.
 type DownloadInfoResponse struct {
+	Provider    string                        `json:"provider"`
 	Fileinfo    client.OneFichierInfoResponse `json:"fileinfo"`
 	Directories map[DownloadType][]string     `json:"directories"`
 }
@@ -1,16 +1,113 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"time"
+)
 
 type Settings struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	APIKey1fichier string    `json:"apiKey1fichier"`
-	APIKeyJellyfin string    `json:"apiKeyJellyfin"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	APIKey1fichier string `json:"apiKey1fichier"`
+	APIKeyJellyfin string `json:"apiKeyJellyfin"`
+
+	// MaxConcurrentDownloads is the number of downloads allowed to run at the same time.
+	// Extra downloads wait in the queue with StatusPending.
+	MaxConcurrentDownloads int `gorm:"default:2" json:"maxConcurrentDownloads"`
+
+	// SegmentsPerDownload is the number of parallel connections used for a single download.
+	// Files too small to be split are downloaded with a single connection.
+	SegmentsPerDownload int `gorm:"default:1" json:"segmentsPerDownload"`
+
+	// SpeedLimit is the bandwidth shared by all downloads, in bytes per second (0 = unlimited).
+	SpeedLimit int64 `gorm:"default:0" json:"speedLimit"`
+
+	// Schedule restricts downloads during some time windows (paused or speed capped).
+	// Outside of every window, downloads run at SpeedLimit.
+	Schedule []ScheduleRule `gorm:"serializer:json" json:"schedule"`
+
+	// ConflictPolicy is applied to the downloads without their own policy, when they
+	// duplicate an existing download or file.
+	ConflictPolicy ConflictPolicy `gorm:"default:FAIL" json:"conflictPolicy"`
+
+	// DiskReserve is the free space kept on the download disk, in bytes (0 = none).
+	// Downloads that don't fit wait in the queue, and running downloads are paused
+	// when the free space drops below it.
+	DiskReserve int64 `gorm:"default:1073741824" json:"diskReserve"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type UpdateSettingsRequest struct {
-	APIKey1fichier string `json:"apiKey1fichier"`
-	APIKeyJellyfin string `json:"apiKeyJellyfin"`
+	APIKey1fichier         string `json:"apiKey1fichier"`
+	APIKeyJellyfin         string `json:"apiKeyJellyfin"`
+	MaxConcurrentDownloads int    `json:"maxConcurrentDownloads"`
+	SegmentsPerDownload    int    `json:"segmentsPerDownload"`
+	SpeedLimit             *int64 `json:"speedLimit"` // nil keeps the current value, 0 = unlimited
+	// nil keeps the current value, an empty list removes the schedule
+	Schedule       []ScheduleRule `gorm:"serializer:json" json:"schedule"`
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy"` // Empty keeps the current value
+	DiskReserve    *int64         `json:"diskReserve"`    // nil keeps the current value, 0 = none
+}
+
+// DefaultConflictPolicy returns the conflict policy of the downloads without their own.
+func (s *Settings) DefaultConflictPolicy() ConflictPolicy {
+	if s.ConflictPolicy == "" {
+		return ConflictFail
+	}
+	return s.ConflictPolicy
+}
+
+// ScheduleRule is a weekly time window during which downloads are paused or speed capped.
+// A window ending before it starts (e.g. 22:00-06:00) spans midnight.
+type ScheduleRule struct {
+	Days       []time.Weekday `json:"days"`       // 0 = Sunday, empty = every day
+	Start      string         `json:"start"`      // HH:MM, inclusive
+	End        string         `json:"end"`        // HH:MM, exclusive
+	Paused     bool           `json:"paused"`     // Pause downloads during the window
+	SpeedLimit int64          `json:"speedLimit"` // Bytes per second when not paused, 0 = unlimited
+}
+
+// ParseClock converts a HH:MM time of day to minutes since midnight.
+func ParseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Matches reports whether t falls into the window. Invalid rules never match.
+func (r ScheduleRule) Matches(t time.Time) bool {
+	start, err := ParseClock(r.Start)
+	if err != nil {
+		return false
+	}
+	end, err := ParseClock(r.End)
+	if err != nil {
+		return false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case start < end:
+		return r.onDay(day) && now >= start && now < end
+	case start > end: // Spans midnight: the end belongs to the next day
+		return (r.onDay(day) && now >= start) || (r.onDay((day+6)%7) && now < end)
+	default: // Same start and end: the whole day
+		return r.onDay(day)
+	}
+}
+
+func (r ScheduleRule) onDay(day time.Weekday) bool {
+	return len(r.Days) == 0 || slices.Contains(r.Days, day)
+}
+
+// ScheduleEvent is the schedule state applied to downloads, sent via SSE on every change.
+type ScheduleEvent struct {
+	Paused     bool          `json:"paused"`
+	SpeedLimit int64         `json:"speedLimit"` // Effective global limit, 0 = unlimited
+	Rule       *ScheduleRule `json:"rule"`       // Active window, nil outside of the schedule
 }
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"dlbackend/internal/database"
+	"dlbackend/internal/model"
+)
+
+type DownloadGroupRepository interface {
+	List() ([]model.DownloadGroup, error)
+	Create(group *model.DownloadGroup) error
+	GetByID(id string) (*model.DownloadGroup, error)
+	Update(group *model.DownloadGroup) error
+	Delete(id string) error
+}
+
+type downloadGroupRepository struct {
+	db *database.Database
+}
+
+func NewDownloadGroupRepository(db *database.Database) DownloadGroupRepository {
+	return &downloadGroupRepository{db: db}
+}
+
+// List returns the groups not archived, most recent first.
+func (r *downloadGroupRepository) List() ([]model.DownloadGroup, error) {
+	var groups []model.DownloadGroup
+	err := r.db.Where("is_archived = ?", false).
+		Order("created_at DESC").
+		Find(&groups).Error
+	return groups, err
+}
+
+func (r *downloadGroupRepository) Create(group *model.DownloadGroup) error {
+	return r.db.Create(group).Error
+}
+
+func (r *downloadGroupRepository) GetByID(id string) (*model.DownloadGroup, error) {
+	var group model.DownloadGroup
+	err := r.db.Where("id = ?", id).First(&group).Error
+	return &group, err
+}
+
+func (r *downloadGroupRepository) Update(group *model.DownloadGroup) error {
+	return r.db.Save(group).Error
+}
+
+func (r *downloadGroupRepository) Delete(id string) error {
+	return r.db.Delete(&model.DownloadGroup{}, "id = ?", id).Error
+}
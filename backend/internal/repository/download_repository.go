@@ -6,11 +6,17 @@ import (
 )
 
 type DownloadRepository interface {
-	List(status []model.DownloadStatus, downloadTypes []model.DownloadType, page, limit int) ([]model.Download, int64, error)
+	List(status []model.DownloadStatus, downloadTypes []model.DownloadType, sort model.DownloadSort, page, limit int) ([]model.Download, int64, error)
 	Create(download *model.Download) error
 	GetByID(id string) (*model.Download, error)
 	Update(download *model.Download) error
 	GetActive() ([]model.Download, error)
+	GetPending() ([]model.Download, error)
+	GetByGroupID(groupID string) ([]model.Download, error)
+	GetByFileURL(fileURL string) ([]model.Download, error)
+	GetByOneFichierFileID(fileID string) ([]model.Download, error)
+	GetByChecksum(checksum string) ([]model.Download, error)
+	GetUnfinished() ([]model.Download, error)
 	Delete(id string) error
 }
 
@@ -22,7 +28,7 @@ func NewDownloadRepository(db *database.Database) DownloadRepository {
 	return &downloadRepository{db: db}
 }
 
-func (r *downloadRepository) List(status []model.DownloadStatus, downloadTypes []model.DownloadType, page, limit int) ([]model.Download, int64, error) {
+func (r *downloadRepository) List(status []model.DownloadStatus, downloadTypes []model.DownloadType, sort model.DownloadSort, page, limit int) ([]model.Download, int64, error) {
 	var downloads []model.Download
 	var total int64
 
@@ -40,8 +46,13 @@ func (r *downloadRepository) List(status []model.DownloadStatus, downloadTypes [
 		return nil, 0, err
 	}
 
+	order := "created_at DESC"
+	if sort == model.SortPriority {
+		order = "priority DESC, created_at ASC"
+	}
+
 	offset := (page - 1) * limit
-	err = query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&downloads).Error
+	err = query.Order(order).Offset(offset).Limit(limit).Find(&downloads).Error
 
 	return downloads, total, err
 }
@@ -53,6 +64,68 @@ func (r *downloadRepository) GetActive() ([]model.Download, error) {
 		model.StatusRequestingInfos,
 		model.StatusRequestingToken,
 		model.StatusDownloading,
+		model.StatusVerifying,
+	}).Find(&downloads).Error
+	return downloads, err
+}
+
+// GetPending returns the queued downloads in the order they should be started:
+// highest priority first, then oldest first.
+func (r *downloadRepository) GetPending() ([]model.Download, error) {
+	var downloads []model.Download
+	err := r.db.Where("status = ? AND is_archived = ?", model.StatusPending, false).
+		Order("priority DESC, created_at ASC").
+		Find(&downloads).Error
+	return downloads, err
+}
+
+// GetByGroupID returns the downloads of a group: the files of a folder link, or the
+// links of a batch creation.
+func (r *downloadRepository) GetByGroupID(groupID string) ([]model.Download, error) {
+	var downloads []model.Download
+	err := r.db.Where("group_id = ?", groupID).
+		Order("created_at ASC").
+		Find(&downloads).Error
+	return downloads, err
+}
+
+// GetByFileURL returns the downloads of a link, archived ones included.
+func (r *downloadRepository) GetByFileURL(fileURL string) ([]model.Download, error) {
+	var downloads []model.Download
+	err := r.db.Where("file_url = ?", fileURL).
+		Order("created_at ASC").
+		Find(&downloads).Error
+	return downloads, err
+}
+
+// GetByOneFichierFileID returns the downloads with a 1fichier link of the file ID
+// (https://1fichier.com/?id or ?id&...), archived ones included. The match is
+// case insensitive, like the file IDs.
+func (r *downloadRepository) GetByOneFichierFileID(fileID string) ([]model.Download, error) {
+	var downloads []model.Download
+	err := r.db.Where("file_url LIKE ? OR file_url LIKE ?", "%?"+fileID, "%?"+fileID+"&%").
+		Order("created_at ASC").
+		Find(&downloads).Error
+	return downloads, err
+}
+
+// GetByChecksum returns the downloads of a file, archived ones included.
+func (r *downloadRepository) GetByChecksum(checksum string) ([]model.Download, error) {
+	var downloads []model.Download
+	err := r.db.Where("checksum = ?", checksum).
+		Order("created_at ASC").
+		Find(&downloads).Error
+	return downloads, err
+}
+
+// GetUnfinished returns the downloads not in a final status, paused ones included.
+func (r *downloadRepository) GetUnfinished() ([]model.Download, error) {
+	var downloads []model.Download
+	err := r.db.Where("status NOT IN ?", []model.DownloadStatus{
+		model.StatusCompleted,
+		model.StatusFailed,
+		model.StatusCorrupted,
+		model.StatusCancelled,
 	}).Find(&downloads).Error
 	return downloads, err
 }
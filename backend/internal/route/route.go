@@ -16,18 +16,33 @@ func SetupRoutes(app *fiber.App, container *container.Container) {
 	settings := api.Group("/settings")
 	settings.Get("/", container.SettingsHandler.GetSettings)
 	settings.Patch("/", container.SettingsHandler.UpdateSettings)
+	settings.Get("/schedule", container.SettingsHandler.GetSchedule)
 
 	// Download routes
 	downloads := api.Group("/downloads")
 	downloads.Get("/infos", container.DownloadHandler.GetInfos)
 	downloads.Get("/", container.DownloadHandler.ListDownloads)
 	downloads.Post("/", container.DownloadHandler.CreateDownload)
+	downloads.Post("/batch", container.DownloadHandler.CreateDownloadBatch)
+	downloads.Put("/queue", container.DownloadHandler.ReorderQueue)
 	downloads.Post("/:id/pause", container.DownloadHandler.PauseDownload)
 	downloads.Post("/:id/resume", container.DownloadHandler.ResumeDownload)
 	downloads.Post("/:id/cancel", container.DownloadHandler.CancelDownload)
+	downloads.Put("/:id/speed-limit", container.DownloadHandler.SetSpeedLimit)
+	downloads.Put("/:id/priority", container.DownloadHandler.SetPriority)
+	downloads.Post("/:id/move-top", container.DownloadHandler.MoveToTop)
+	downloads.Post("/:id/move-bottom", container.DownloadHandler.MoveToBottom)
 	downloads.Post("/:id/archive", container.DownloadHandler.ArchiveDownload)
 	downloads.Delete("/:id", container.DownloadHandler.DeleteDownload)
 
+	// Download group routes
+	groups := downloads.Group("/groups")
+	groups.Get("/", container.DownloadGroupHandler.ListGroups)
+	groups.Post("/:id/pause", container.DownloadGroupHandler.PauseGroup)
+	groups.Post("/:id/resume", container.DownloadGroupHandler.ResumeGroup)
+	groups.Post("/:id/cancel", container.DownloadGroupHandler.CancelGroup)
+	groups.Post("/:id/archive", container.DownloadGroupHandler.ArchiveGroup)
+
 	// Download SSE routes
 	downloads.Get("/streams", container.SSEManager.Handler)
 
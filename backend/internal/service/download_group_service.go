@@ -0,0 +1,118 @@
+package service
+
+import (
+	"dlbackend/internal/errors"
+	"dlbackend/internal/model"
+	"dlbackend/internal/repository"
+	"dlbackend/pkg/worker"
+	stderrors "errors"
+	"fmt"
+)
+
+type DownloadGroupService interface {
+	ListGroups() ([]model.DownloadGroup, error)
+	PauseGroup(id string) error
+	ResumeGroup(id string) error
+	CancelGroup(id string) error
+	ArchiveGroup(id string) error
+}
+
+type downloadGroupService struct {
+	groupRepo    repository.DownloadGroupRepository
+	downloadRepo repository.DownloadRepository
+	dlManager    *worker.DownloadManager
+}
+
+func NewDownloadGroupService(
+	groupRepo repository.DownloadGroupRepository,
+	downloadRepo repository.DownloadRepository,
+	dlManager *worker.DownloadManager,
+) DownloadGroupService {
+	return &downloadGroupService{
+		groupRepo:    groupRepo,
+		downloadRepo: downloadRepo,
+		dlManager:    dlManager,
+	}
+}
+
+// ListGroups returns the groups not archived with the aggregate progress of their downloads.
+func (gs *downloadGroupService) ListGroups() ([]model.DownloadGroup, error) {
+	groups, err := gs.groupRepo.List()
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("failed to list groups: %v", err))
+	}
+
+	for i := range groups {
+		downloads, err := gs.downloadRepo.GetByGroupID(groups[i].ID)
+		if err != nil {
+			return nil, errors.Internal(fmt.Sprintf("failed to get group downloads: %v", err))
+		}
+		groups[i].Progress = model.NewDownloadGroupProgress(downloads)
+	}
+
+	return groups, nil
+}
+
+func (gs *downloadGroupService) PauseGroup(id string) error {
+	return gs.forEachInGroup(id, "failed to pause download", gs.dlManager.Pause)
+}
+
+func (gs *downloadGroupService) ResumeGroup(id string) error {
+	return gs.forEachInGroup(id, "failed to resume download", gs.dlManager.Resume)
+}
+
+func (gs *downloadGroupService) CancelGroup(id string) error {
+	return gs.forEachInGroup(id, "failed to cancel download", gs.dlManager.Cancel)
+}
+
+// ArchiveGroup archives the group and all its downloads, once all of them are finished.
+func (gs *downloadGroupService) ArchiveGroup(id string) error {
+	group, err := gs.groupRepo.GetByID(id)
+	if err != nil {
+		return errors.NotFound(fmt.Sprintf("group %s not found", id))
+	}
+	downloads, err := gs.downloadRepo.GetByGroupID(id)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("failed to get group downloads: %v", err))
+	}
+
+	for _, download := range downloads {
+		if !download.Status.IsFinished() {
+			return errors.Conflict(fmt.Sprintf("can't archive a group with active downloads. download %s state %s", download.ID, download.Status))
+		}
+	}
+	for i := range downloads {
+		downloads[i].IsArchived = true
+		if err := gs.downloadRepo.Update(&downloads[i]); err != nil {
+			return err
+		}
+	}
+
+	group.IsArchived = true
+	return gs.groupRepo.Update(group)
+}
+
+// ============================================================================
+// PRIVATE METHODS
+// ============================================================================
+
+// forEachInGroup applies a DownloadManager action to all downloads of a group.
+// Downloads in a state where the action does not apply (e.g. pausing a
+// completed download) are left as they are.
+func (gs *downloadGroupService) forEachInGroup(id string, msg string, action func(id string) error) error {
+	if _, err := gs.groupRepo.GetByID(id); err != nil {
+		return errors.NotFound(fmt.Sprintf("group %s not found", id))
+	}
+	downloads, err := gs.downloadRepo.GetByGroupID(id)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("failed to get group downloads: %v", err))
+	}
+
+	for _, download := range downloads {
+		err := action(download.ID)
+		if err != nil && !stderrors.Is(err, worker.ErrInvalidTransition) {
+			return downloadStateError(msg, err)
+		}
+	}
+	return nil
+}
@@ -10,9 +10,12 @@ import (
 	"dlbackend/pkg/client"
 	"dlbackend/pkg/sse"
 	"dlbackend/pkg/worker"
+	stderrors "errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v3/log"
@@ -20,53 +23,89 @@ import (
 )
 
 type DownloadService interface {
-	GetFileinfo(fileURL string) (*model.DownloadInfoResponse, error)
-	ListDownloads(status []model.DownloadStatus, downloadType []model.DownloadType, page, limit int) ([]model.Download, int64, error)
-	CreateDownload(fileURL string, downloadType model.DownloadType, dirName string, fileName string) (*model.Download, error)
+	GetFileinfo(ctx context.Context, fileURL string) (*model.DownloadInfoResponse, error)
+	ListDownloads(status []model.DownloadStatus, downloadType []model.DownloadType, sort model.DownloadSort, page, limit int) ([]model.Download, int64, error)
+	CreateDownload(ctx context.Context, fileURL string, downloadType model.DownloadType, dirName string, fileName string, groupID *string, onConflict *model.ConflictPolicy, password string) (*model.Download, []model.DownloadDuplicate, error)
+	CreateFolderDownloads(ctx context.Context, folderURL string, downloadType model.DownloadType, dirName string, onConflict *model.ConflictPolicy) (*model.DownloadGroup, []*model.Download, []model.DownloadDuplicate, error)
+	CreateGroup(name string, downloadType model.DownloadType, dirName string) (*model.DownloadGroup, error)
+	DeleteEmptyGroup(id string) bool
 	PauseDownload(id string) error
 	ResumeDownload(id string) error
 	CancelDownload(id string) error
+	SetSpeedLimit(id string, speedLimit *int64) error
+	SetPriority(id string, priority int) error
+	MoveToTop(id string) error
+	MoveToBottom(id string) error
+	ReorderQueue(ids []string) error
 	ArchiveDownload(id string) error
 	DeleteDownload(id string) error
 }
 
 type downloadService struct {
 	downloadRepo repository.DownloadRepository
+	groupRepo    repository.DownloadGroupRepository
 	settingsRepo repository.SettingsRepository
 	filesService FilesService
 	sseManager   sse.Manager
 	dlManager    *worker.DownloadManager
+	providers    client.Providers
+	oneFichier   client.OneFichierClient
 }
 
 func NewDownloadService(
 	downloadRepo repository.DownloadRepository,
+	groupRepo repository.DownloadGroupRepository,
 	settingsRepo repository.SettingsRepository,
 	filesService FilesService,
 	sseManager sse.Manager,
+	dlManager *worker.DownloadManager,
+	providers client.Providers,
+	oneFichier client.OneFichierClient,
 ) DownloadService {
 	return &downloadService{
 		downloadRepo: downloadRepo,
+		groupRepo:    groupRepo,
 		settingsRepo: settingsRepo,
 		filesService: filesService,
 		sseManager:   sseManager,
-		dlManager:    worker.NewDownloadManager(context.Background(), downloadRepo, settingsRepo, sseManager),
+		dlManager:    dlManager,
+		providers:    providers,
+		oneFichier:   oneFichier,
 	}
 }
 
-func (ds *downloadService) GetFileinfo(fileURL string) (*model.DownloadInfoResponse, error) {
+func (ds *downloadService) GetFileinfo(ctx context.Context, fileURL string) (*model.DownloadInfoResponse, error) {
 	settings, err := ds.settingsRepo.Get()
 	if err != nil {
 		return nil, errors.Internal(fmt.Sprintf("failed to load settings: %v", err))
 	}
-	if settings.APIKey1fichier == "" {
-		return nil, errors.Internal("1fichier API key not configured")
+	provider, err := ds.providerFor(fileURL, settings)
+	if err != nil {
+		return nil, err
 	}
 
-	oneFichierClient := client.NewOneFichierClient(config.Cfg.ApiUrl1fichier, settings.APIKey1fichier)
-	fileinfo, err := oneFichierClient.GetFileInfo(fileURL)
+	// A protected file is reported with pass=1, so the password can be asked before creating the download
+	var fileinfo *client.OneFichierInfoResponse
+	if provider.Name() == client.ProviderOneFichier {
+		fileinfo, err = ds.oneFichier.GetFileInfo(ctx, fileURL, "")
+		if stderrors.Is(err, client.ErrPasswordRequired) {
+			fileinfo, err = &client.OneFichierInfoResponse{URL: fileURL, Pass: 1}, nil
+		}
+	} else {
+		var info *client.FileInfo
+		if info, err = provider.GetFileInfo(ctx, fileURL, ""); err == nil {
+			fileinfo = &client.OneFichierInfoResponse{
+				URL:         fileURL,
+				Filename:    info.Filename,
+				Size:        info.Size,
+				Checksum:    info.Checksum,
+				ContentType: info.ContentType,
+			}
+		}
+	}
 	if err != nil {
 		log.Error(err)
-		return nil, errors.Internal("failed to retrieve file info from 1fichier API")
+		return nil, providerError(fmt.Sprintf("failed to retrieve file info from %s", provider.Name()), err)
 	}
 
 	moviePath := filepath.Join(config.Cfg.DLPath, model.TypeMovie.Dir())
@@ -82,6 +121,7 @@ func (ds *downloadService) GetFileinfo(fileURL string) (*model.DownloadInfoRespo
 	}
 
 	return &model.DownloadInfoResponse{
+		Provider: provider.Name(),
 		Fileinfo: *fileinfo,
 		Directories: map[model.DownloadType][]string{
 			model.TypeMovie: movieDirectories,
@@ -90,26 +130,34 @@ func (ds *downloadService) GetFileinfo(fileURL string) (*model.DownloadInfoRespo
 	}, nil
 }
 
-func (ds *downloadService) ListDownloads(status []model.DownloadStatus, downloadTypes []model.DownloadType, page, limit int) ([]model.Download, int64, error) {
-	return ds.downloadRepo.List(status, downloadTypes, page, limit)
+func (ds *downloadService) ListDownloads(status []model.DownloadStatus, downloadTypes []model.DownloadType, sort model.DownloadSort, page, limit int) ([]model.Download, int64, error) {
+	return ds.downloadRepo.List(status, downloadTypes, sort, page, limit)
 }
 
-func (ds *downloadService) CreateDownload(fileURL string, downloadType model.DownloadType, customFileDir string, customFileName string) (*model.Download, error) {
+// CreateDownload creates and queues a download. Duplicates of an existing download
+// or file are handled by the conflict policy: onConflict, or the default one from
+// the settings. A skipped download is returned as nil, along with its duplicates.
+// The password of a protected file is stored encrypted ("" if not protected).
+func (ds *downloadService) CreateDownload(ctx context.Context, fileURL string, downloadType model.DownloadType, customFileDir string, customFileName string, groupID *string, onConflict *model.ConflictPolicy, password string) (*model.Download, []model.DownloadDuplicate, error) {
 	settings, err := ds.settingsRepo.Get()
 	if err != nil {
-		return nil, errors.Internal(fmt.Sprintf("failed to load settings: %v", err))
+		return nil, nil, errors.Internal(fmt.Sprintf("failed to load settings: %v", err))
 	}
-	if settings.APIKey1fichier == "" {
-		return nil, errors.Internal("1fichier API key not configured")
+	provider, err := ds.providerFor(fileURL, settings)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Create Download
 	download := &model.Download{
 		ID:              uuid.New().String(),
 		FileURL:         fileURL,
+		Provider:        provider.Name(),
 		CustomFileDir:   &customFileDir,
 		CustomFileName:  &customFileName,
 		Type:            downloadType,
+		GroupID:         groupID,
+		OnConflict:      onConflict,
 		Status:          model.StatusPending,
 		Progress:        0,
 		DownloadedBytes: 0,
@@ -119,48 +167,486 @@ func (ds *downloadService) CreateDownload(fileURL string, downloadType model.Dow
 		IsArchived:      false,
 	}
 
+	if password != "" {
+		encrypted, err := utils.EncryptSecret(password)
+		if err != nil {
+			return nil, nil, errors.Internal(fmt.Sprintf("failed to encrypt password: %v", err))
+		}
+		download.Password = &encrypted
+	}
+
+	// File infos are needed to detect duplicates by checksum and final path: the 1fichier
+	// ones come from the cache filled when the link was pasted. A download that can't
+	// succeed (bad API key, deleted file, missing password) is not created. The worker
+	// checks the duplicates again when it starts, in case the lookup failed here.
+	if info, err := provider.GetFileInfo(ctx, fileURL, password); err != nil {
+		if stderrors.Is(err, client.ErrUnauthorized) || stderrors.Is(err, client.ErrFileNotFound) ||
+			stderrors.Is(err, client.ErrPasswordRequired) || stderrors.Is(err, client.ErrInvalidPassword) {
+			return nil, nil, providerError(fmt.Sprintf("failed to retrieve file info from %s", provider.Name()), err)
+		}
+		log.Warnf("Failed to get file info of %s, duplicates checked by URL only: %v", fileURL, err)
+	} else {
+		download.FileName = info.Filename
+		download.FileSize = &info.Size
+		download.Checksum = &info.Checksum
+		if info.ContentType != "" {
+			download.MimeType = &info.ContentType
+		}
+	}
+
+	duplicates, err := ds.findDuplicates(download)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(duplicates) > 0 {
+		policy := download.ResolveConflictPolicy(settings.DefaultConflictPolicy())
+		create, err := ds.applyConflictPolicy(download, policy, duplicates)
+		if err != nil || !create {
+			return nil, duplicates, err
+		}
+	}
+
 	if err := ds.downloadRepo.Create(download); err != nil {
+		return nil, duplicates, err
+	}
+
+	// Queue download: the scheduler starts it as soon as a slot is free
+	ds.dlManager.Schedule()
+
+	return download, duplicates, nil
+}
+
+// CreateGroup creates an empty download group, e.g. for a batch of links.
+func (ds *downloadService) CreateGroup(name string, downloadType model.DownloadType, customFileDir string) (*model.DownloadGroup, error) {
+	return ds.createGroup(name, downloadType, customFileDir, nil)
+}
+
+// DeleteEmptyGroup deletes a download group without any download, e.g. the group of a
+// batch whose links all failed or were skipped. It reports whether the group was deleted;
+// a failure is only logged, the group is then kept.
+func (ds *downloadService) DeleteEmptyGroup(id string) bool {
+	downloads, err := ds.downloadRepo.GetByGroupID(id)
+	if err != nil {
+		log.Warnf("Failed to get the downloads of group %s: %v", id, err)
+		return false
+	}
+	if len(downloads) > 0 {
+		return false
+	}
+	if err := ds.groupRepo.Delete(id); err != nil {
+		log.Warnf("Failed to delete empty group %s: %v", id, err)
+		return false
+	}
+	return true
+}
+
+func (ds *downloadService) createGroup(name string, downloadType model.DownloadType, customFileDir string, folderURL *string) (*model.DownloadGroup, error) {
+	group := &model.DownloadGroup{
+		ID:         uuid.New().String(),
+		Name:       name,
+		Type:       downloadType,
+		FileDir:    customFileDir,
+		FolderURL:  folderURL,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		IsArchived: false,
+	}
+
+	if err := ds.groupRepo.Create(group); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("failed to create group: %v", err))
+	}
+
+	return group, nil
+}
+
+// CreateFolderDownloads expands a 1fichier folder link into one download per file,
+// grouped under a common download group so they can be managed together.
+// If any file is a duplicate and the conflict policy is FAIL, nothing is created;
+// the group is not created when all the files are skipped.
+func (ds *downloadService) CreateFolderDownloads(ctx context.Context, folderURL string, downloadType model.DownloadType, customFileDir string, onConflict *model.ConflictPolicy) (*model.DownloadGroup, []*model.Download, []model.DownloadDuplicate, error) {
+	settings, err := ds.settingsRepo.Get()
+	if err != nil {
+		return nil, nil, nil, errors.Internal(fmt.Sprintf("failed to load settings: %v", err))
+	}
+	if settings.APIKey1fichier == "" {
+		return nil, nil, nil, errors.Internal("1fichier API key not configured")
+	}
+
+	entries, err := ds.oneFichier.ListFolder(ctx, folderURL)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, nil, providerError("failed to list 1fichier folder", err)
+	}
+
+	// Keep the valid file links only
+	var files []*model.Download
+	for _, entry := range entries {
+		fileURL, err := utils.Validate1FichierURL(entry.Link)
+		if err != nil {
+			log.Warnf("Skipping invalid link %q of folder %s: %v", entry.Link, folderURL, err)
+			continue
+		}
+
+		download := &model.Download{
+			ID:              uuid.New().String(),
+			FileURL:         fileURL,
+			Provider:        client.ProviderOneFichier,
+			CustomFileDir:   &customFileDir,
+			Type:            downloadType,
+			FileName:        entry.Filename,
+			OnConflict:      onConflict,
+			Status:          model.StatusPending,
+			Progress:        0,
+			DownloadedBytes: 0,
+			RetryCount:      0,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+			IsArchived:      false,
+		}
+		if entry.Size > 0 {
+			download.FileSize = &entry.Size
+		}
+		files = append(files, download)
+	}
+	if len(files) == 0 {
+		return nil, nil, nil, errors.BadRequest("the folder contains no file")
+	}
+
+	// Check all the files before creating anything
+	var duplicates []model.DownloadDuplicate
+	duplicated := make(map[string]bool, len(files)) // download ID -> has duplicates
+	for _, download := range files {
+		found, err := ds.findDuplicates(download)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		duplicates = append(duplicates, found...)
+		duplicated[download.ID] = len(found) > 0
+	}
+	policy := settings.DefaultConflictPolicy()
+	if onConflict != nil {
+		policy = *onConflict
+	}
+	if len(duplicates) > 0 && policy == model.ConflictFail {
+		return nil, nil, duplicates, errors.Conflict("folder contains downloads that already exist").WithDetails(duplicates)
+	}
+
+	var group *model.DownloadGroup // Created with the first download
+	downloads := make([]*model.Download, 0, len(files))
+	for _, download := range files {
+		if duplicated[download.ID] {
+			create, err := ds.applyConflictPolicy(download, policy, duplicates)
+			if err != nil {
+				return group, downloads, duplicates, err
+			}
+			if !create {
+				continue
+			}
+		}
+
+		if group == nil {
+			folderName := "Folder " + path.Base(strings.TrimSuffix(folderURL, "/"))
+			if group, err = ds.createGroup(folderName, downloadType, customFileDir, &folderURL); err != nil {
+				return nil, nil, duplicates, err
+			}
+		}
+		download.GroupID = &group.ID
+
+		if err := ds.downloadRepo.Create(download); err != nil {
+			return group, downloads, duplicates, err
+		}
+		downloads = append(downloads, download)
+	}
+
+	// Queue downloads: the scheduler starts them as soon as slots are free
+	ds.dlManager.Schedule()
+
+	return group, downloads, duplicates, nil
+}
+
+// findDuplicates lists what a new download conflicts with: the same link already
+// active or completed, the same file (checksum) already completed, or the same
+// final path already on disk or used by an unfinished download.
+func (ds *downloadService) findDuplicates(download *model.Download) ([]model.DownloadDuplicate, error) {
+	var duplicates []model.DownloadDuplicate
+	seen := make(map[string]bool) // Report each existing download once
+	add := func(reason model.DuplicateReason, existing *model.Download, path string) {
+		duplicate := model.DownloadDuplicate{Reason: reason, URL: download.FileURL, Path: path}
+		if existing != nil {
+			if seen[existing.ID] {
+				return
+			}
+			seen[existing.ID] = true
+			duplicate.DownloadID = &existing.ID
+			duplicate.Status = &existing.Status
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	sameURL, err := ds.sameFileURL(download)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("failed to check duplicates: %v", err))
+	}
+	for i := range sameURL {
+		if sameURL[i].ID != download.ID && (!sameURL[i].Status.IsFinished() || sameURL[i].Status == model.StatusCompleted) {
+			add(model.DuplicateURL, &sameURL[i], "")
+		}
+	}
+
+	if download.Checksum != nil && *download.Checksum != "" {
+		sameChecksum, err := ds.downloadRepo.GetByChecksum(*download.Checksum)
+		if err != nil {
+			return nil, errors.Internal(fmt.Sprintf("failed to check duplicates: %v", err))
+		}
+		for i := range sameChecksum {
+			if sameChecksum[i].ID != download.ID && sameChecksum[i].Status == model.StatusCompleted {
+				add(model.DuplicateChecksum, &sameChecksum[i], "")
+			}
+		}
+	}
+
+	if !download.HasFileName() {
+		return duplicates, nil
+	}
+	finalPath, err := download.FinalFilePath()
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("failed to resolve final path: %v", err))
+	}
+	unfinished, err := ds.downloadRepo.GetUnfinished()
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("failed to check duplicates: %v", err))
+	}
+	for i := range unfinished {
+		if unfinished[i].ID == download.ID || !unfinished[i].HasFileName() {
+			continue
+		}
+		if otherPath, _ := unfinished[i].FinalFilePath(); otherPath == finalPath {
+			add(model.DuplicateFile, &unfinished[i], finalPath)
+		}
+	}
+	if _, err := os.Stat(finalPath); err == nil {
+		add(model.DuplicateFile, nil, finalPath)
+	}
+
+	return duplicates, nil
+}
+
+// sameFileURL returns the downloads of the same link. The 1fichier links are compared
+// by file ID, e.g. with or without the affiliate parameter.
+func (ds *downloadService) sameFileURL(download *model.Download) ([]model.Download, error) {
+	if download.Provider != client.ProviderOneFichier {
+		return ds.downloadRepo.GetByFileURL(download.FileURL)
+	}
+	fileID, err := client.OneFichierFileID(download.FileURL)
+	if err != nil {
+		return ds.downloadRepo.GetByFileURL(download.FileURL)
+	}
+	candidates, err := ds.downloadRepo.GetByOneFichierFileID(fileID)
+	if err != nil {
 		return nil, err
 	}
 
-	// Start download
-	if err := ds.dlManager.Start(download.Clone()); err != nil {
-		return nil, errors.Internal(fmt.Sprintf("failed to Start download: %v", err))
+	var downloads []model.Download
+	for _, candidate := range candidates {
+		if id, err := client.OneFichierFileID(candidate.FileURL); err == nil && id == fileID && utils.Is1FichierURL(candidate.FileURL) {
+			downloads = append(downloads, candidate)
+		}
+	}
+	return downloads, nil
+}
+
+// applyConflictPolicy resolves the duplicates of a new download according to the policy.
+// It returns false if the download must not be created (SKIP), or a conflict error (FAIL).
+func (ds *downloadService) applyConflictPolicy(download *model.Download, policy model.ConflictPolicy, duplicates []model.DownloadDuplicate) (bool, error) {
+	switch policy {
+	case model.ConflictSkip:
+		log.Infof("Skipping duplicated download %s", download.FileURL)
+		return false, nil
+	case model.ConflictRename, model.ConflictOverwrite:
+		if !download.HasFileName() {
+			return true, nil
+		}
+		reserved, err := ds.reservedPaths()
+		if err != nil {
+			return false, err
+		}
+		finalPath, err := download.FinalFilePath()
+		if err != nil {
+			return false, errors.Internal(fmt.Sprintf("failed to resolve final path: %v", err))
+		}
+
+		// Both downloads would write the same temp file
+		if policy == model.ConflictOverwrite {
+			if reserved[finalPath] {
+				return false, errors.Conflict("cannot overwrite a file still being downloaded").WithDetails(duplicates)
+			}
+			return true, nil
+		}
+
+		// Keep both: pick a name free on disk and unused by the unfinished downloads
+		if availablePath := utils.AvailablePath(finalPath, func(p string) bool { return reserved[p] }); availablePath != finalPath {
+			fileName := filepath.Base(availablePath)
+			outcome := model.OutcomeRenamed
+			download.CustomFileName = &fileName
+			download.ConflictOutcome = &outcome
+		}
+		return true, nil
+	default:
+		return false, errors.Conflict("download already exists").WithDetails(duplicates)
+	}
+}
+
+// reservedPaths returns the final paths of the unfinished downloads.
+func (ds *downloadService) reservedPaths() (map[string]bool, error) {
+	unfinished, err := ds.downloadRepo.GetUnfinished()
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("failed to check duplicates: %v", err))
 	}
 
-	return download, nil
+	reserved := make(map[string]bool, len(unfinished))
+	for i := range unfinished {
+		if unfinished[i].HasFileName() {
+			finalPath, _ := unfinished[i].FinalFilePath()
+			reserved[finalPath] = true
+		}
+	}
+	return reserved, nil
 }
 
 func (ds *downloadService) PauseDownload(id string) error {
 	if err := ds.dlManager.Pause(id); err != nil {
-		return errors.Internal(fmt.Sprintf("failed to pause download: %v", err))
+		return downloadStateError("failed to pause download", err)
 	}
 	return nil
 }
 
 func (ds *downloadService) ResumeDownload(id string) error {
 	if err := ds.dlManager.Resume(id); err != nil {
-		return errors.Internal(fmt.Sprintf("failed to resume download: %v", err))
+		return downloadStateError("failed to resume download", err)
 	}
 	return nil
 }
 
 func (ds *downloadService) CancelDownload(id string) error {
 	if err := ds.dlManager.Cancel(id); err != nil {
-		return errors.Internal(fmt.Sprintf("failed to cancel download: %v", err))
+		return downloadStateError("failed to cancel download", err)
+	}
+	return nil
+}
+
+func (ds *downloadService) SetSpeedLimit(id string, speedLimit *int64) error {
+	if err := ds.dlManager.SetSpeedLimit(id, speedLimit); err != nil {
+		return downloadStateError("failed to set speed limit", err)
 	}
 	return nil
 }
 
+func (ds *downloadService) SetPriority(id string, priority int) error {
+	if err := ds.dlManager.SetPriority(id, priority); err != nil {
+		return downloadStateError("failed to set priority", err)
+	}
+	return nil
+}
+
+// MoveToTop gives the download a priority above all the other pending downloads.
+func (ds *downloadService) MoveToTop(id string) error {
+	download, pending, err := ds.queuedDownload(id)
+	if err != nil {
+		return err
+	}
+
+	priority := download.Priority
+	for _, other := range pending {
+		if other.ID != id {
+			priority = max(priority, other.Priority+1)
+		}
+	}
+	return ds.SetPriority(id, priority)
+}
+
+// MoveToBottom gives the download a priority below all the other pending downloads.
+func (ds *downloadService) MoveToBottom(id string) error {
+	download, pending, err := ds.queuedDownload(id)
+	if err != nil {
+		return err
+	}
+
+	priority := download.Priority
+	for _, other := range pending {
+		if other.ID != id {
+			priority = min(priority, other.Priority-1)
+		}
+	}
+	return ds.SetPriority(id, priority)
+}
+
+// ReorderQueue puts the given pending downloads first, in this order, followed by the
+// other pending downloads in their current order. Priorities are renumbered from the
+// queue length down to 1, so new downloads (priority 0) still go last.
+func (ds *downloadService) ReorderQueue(ids []string) error {
+	pending, err := ds.downloadRepo.GetPending()
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("failed to get pending downloads: %v", err))
+	}
+
+	queued := make(map[string]*model.Download, len(pending))
+	for i := range pending {
+		queued[pending[i].ID] = &pending[i]
+	}
+
+	ordered := make([]*model.Download, 0, len(pending))
+	moved := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if moved[id] {
+			return errors.BadRequest(fmt.Sprintf("download %s is listed twice", id))
+		}
+		download, ok := queued[id]
+		if !ok {
+			return errors.Conflict(fmt.Sprintf("download %s is not queued", id))
+		}
+		ordered = append(ordered, download)
+		moved[id] = true
+	}
+	for i := range pending {
+		if !moved[pending[i].ID] {
+			ordered = append(ordered, &pending[i])
+		}
+	}
+
+	for i, download := range ordered {
+		if priority := len(ordered) - i; download.Priority != priority {
+			if err := ds.SetPriority(download.ID, priority); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// queuedDownload returns a pending download along with the whole pending queue.
+func (ds *downloadService) queuedDownload(id string) (*model.Download, []model.Download, error) {
+	download, err := ds.downloadRepo.GetByID(id)
+	if err != nil {
+		return nil, nil, errors.NotFound("download not found")
+	}
+	if download.Status != model.StatusPending {
+		return nil, nil, errors.Conflict("download is not queued")
+	}
+
+	pending, err := ds.downloadRepo.GetPending()
+	if err != nil {
+		return nil, nil, errors.Internal(fmt.Sprintf("failed to get pending downloads: %v", err))
+	}
+	return download, pending, nil
+}
+
 func (ds *downloadService) ArchiveDownload(id string) error {
 	download, err := ds.downloadRepo.GetByID(id)
 	if err != nil {
 		return err
 	}
-	if download.Status != model.StatusCompleted &&
-		download.Status != model.StatusFailed &&
-		download.Status != model.StatusCancelled {
-		return fmt.Errorf("can't archive an active download. current state %s", download.Status)
+	if !download.Status.IsFinished() {
+		return errors.Conflict(fmt.Sprintf("can't archive an active download. current state %s", download.Status))
 	}
 	download.IsArchived = true
 	return ds.downloadRepo.Update(download)
@@ -172,28 +658,21 @@ func (ds *downloadService) DeleteDownload(id string) error {
 		return err
 	}
 
-	// Delete final file if it exists.
-	// NOTE: the || condition is intentional — returns early on path resolution error
-	// OR when finalPath is non-empty (which currently never happens due to the bug below).
-	// TODO: this should be `if err != nil` only; the current logic prevents file deletion.
+	// Delete the final file, unless the download skipped it: the file is not its own
 	finalPath, err := download.FinalFilePath()
-	if finalPath != "" || err != nil {
+	if err != nil {
 		return err
 	}
-	if download.Status == model.StatusCompleted {
+	if download.OwnsFinalFile() {
 		if err := os.Remove(finalPath); err != nil && !os.IsNotExist(err) {
 			log.Warnf("Failed to delete file %s: %v", finalPath, err)
 		}
 	}
 
-	// Delete temp file if exist
-	tempPath, err := download.TempFilePath()
-	if tempPath != "" && err != nil {
+	// Delete the temp file and its checkpoint
+	if err := worker.RemoveTempFiles(download); err != nil {
 		return err
 	}
-	if err := os.Remove(tempPath); err != nil && !os.IsNotExist(err) {
-		log.Warnf("Failed to delete temp file %s: %v", tempPath, err)
-	}
 
 	return ds.downloadRepo.Delete(id)
 }
@@ -202,6 +681,51 @@ func (ds *downloadService) DeleteDownload(id string) error {
 // PRIVATE METHODS
 // ============================================================================
 
+// downloadStateError maps a DownloadManager error to its HTTP error:
+// unknown download (404), invalid state transition (409), others (500).
+func downloadStateError(msg string, err error) error {
+	switch {
+	case stderrors.Is(err, worker.ErrDownloadNotFound):
+		return errors.NotFound(fmt.Sprintf("%s: %v", msg, err))
+	case stderrors.Is(err, worker.ErrInvalidTransition):
+		return errors.Conflict(fmt.Sprintf("%s: %v", msg, err))
+	default:
+		return errors.Internal(fmt.Sprintf("%s: %v", msg, err))
+	}
+}
+
+// providerFor returns the provider of the link, the 1fichier one needs the API key.
+func (ds *downloadService) providerFor(fileURL string, settings *model.Settings) (client.Provider, error) {
+	provider, err := ds.providers.ForURL(fileURL)
+	if err != nil {
+		return nil, errors.BadRequest(err.Error())
+	}
+	if provider.Name() == client.ProviderOneFichier && settings.APIKey1fichier == "" {
+		return nil, errors.Internal("1fichier API key not configured")
+	}
+	return provider, nil
+}
+
+// providerError maps a provider error to its HTTP error: private network address (400),
+// API key rejected (401), password required (403), file not found (404), flood
+// protection (429), others (500).
+func providerError(msg string, err error) error {
+	switch {
+	case stderrors.Is(err, client.ErrPrivateAddress):
+		return errors.BadRequest(fmt.Sprintf("%s: %v", msg, err))
+	case stderrors.Is(err, client.ErrUnauthorized):
+		return errors.Unauthorized(fmt.Sprintf("%s: 1fichier API key rejected", msg))
+	case stderrors.Is(err, client.ErrPasswordRequired), stderrors.Is(err, client.ErrInvalidPassword):
+		return errors.Forbidden(fmt.Sprintf("%s: %v", msg, err))
+	case stderrors.Is(err, client.ErrFileNotFound):
+		return errors.NotFound(fmt.Sprintf("%s: %v", msg, err))
+	case stderrors.Is(err, client.ErrRateLimited):
+		return errors.TooManyRequests(fmt.Sprintf("%s: %v", msg, err))
+	default:
+		return errors.Internal(msg)
+	}
+}
+
 // cleanupTempFile removes the temporary download file.
 // func (ds *downloadService) cleanupTempFile(download *model.Download) {
 // 	if download.TempPath != nil && *download.TempPath != "" {
@@ -4,20 +4,23 @@ import (
 	"dlbackend/internal/errors"
 	"dlbackend/internal/model"
 	"dlbackend/internal/repository"
+	"dlbackend/pkg/worker"
 	"fmt"
 )
 
 type SettingsService interface {
 	GetSettings() (*model.Settings, error)
 	UpdateSettings(settings *model.UpdateSettingsRequest) (*model.Settings, error)
+	GetScheduleState() model.ScheduleEvent
 }
 
 type settingsService struct {
-	repo repository.SettingsRepository
+	repo      repository.SettingsRepository
+	dlManager *worker.DownloadManager
 }
 
-func NewSettingsService(repo repository.SettingsRepository) SettingsService {
-	return &settingsService{repo: repo}
+func NewSettingsService(repo repository.SettingsRepository, dlManager *worker.DownloadManager) SettingsService {
+	return &settingsService{repo: repo, dlManager: dlManager}
 }
 
 func (ss *settingsService) GetSettings() (*model.Settings, error) {
@@ -39,5 +42,12 @@ func (ss *settingsService) UpdateSettings(settings *model.UpdateSettingsRequest)
 		return nil, errors.Internal(fmt.Sprintf("failed to retrieve settings: %v", err))
 	}
 
+	// Apply a new concurrency limit, speed limit or schedule right away
+	ss.dlManager.Schedule()
+
 	return updated, nil
 }
+
+func (ss *settingsService) GetScheduleState() model.ScheduleEvent {
+	return ss.dlManager.ScheduleState()
+}
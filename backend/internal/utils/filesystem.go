@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // ============================================================================
@@ -34,6 +35,32 @@ func MoveFile(src, dst string) error {
 	return nil
 }
 
+// AvailablePath returns the path itself if it's free, or the first free numbered
+// variant ("name (1).ext", "name (2).ext", ...). A path is free if it doesn't exist
+// on disk and isn't reserved (reserved may be nil).
+func AvailablePath(path string, reserved func(string) bool) string {
+	free := func(p string) bool {
+		if reserved != nil && reserved(p) {
+			return false
+		}
+		_, err := os.Lstat(p)
+		return os.IsNotExist(err)
+	}
+
+	if free(path) {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if free(candidate) {
+			return candidate
+		}
+	}
+}
+
 // SamePath reports whether two paths (relative or absolute) point to the same file or directory.
 func SamePath(path1, path2 string) (bool, error) {
 	// Resolve both paths to absolute
@@ -121,6 +121,71 @@ func TestMoveFile(t *testing.T) {
 	}
 }
 
+func TestAvailablePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		reserved []string
+		path     string
+		want     string
+	}{
+		{
+			name: "free path",
+			path: "movie.mkv",
+			want: "movie.mkv",
+		},
+		{
+			name:     "existing file",
+			existing: []string{"movie.mkv"},
+			path:     "movie.mkv",
+			want:     "movie (1).mkv",
+		},
+		{
+			name:     "existing numbered files",
+			existing: []string{"movie.mkv", "movie (1).mkv", "movie (2).mkv"},
+			path:     "movie.mkv",
+			want:     "movie (3).mkv",
+		},
+		{
+			name:     "reserved path",
+			existing: []string{"movie.mkv"},
+			reserved: []string{"movie (1).mkv"},
+			path:     "movie.mkv",
+			want:     "movie (2).mkv",
+		},
+		{
+			name:     "no extension",
+			existing: []string{"movie"},
+			path:     "movie",
+			want:     "movie (1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("test content"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			reserved := func(path string) bool {
+				for _, name := range tt.reserved {
+					if path == filepath.Join(tmpDir, name) {
+						return true
+					}
+				}
+				return false
+			}
+
+			got := AvailablePath(filepath.Join(tmpDir, tt.path), reserved)
+			if want := filepath.Join(tmpDir, tt.want); got != want {
+				t.Errorf("AvailablePath() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func TestSamePath(t *testing.T) {
 	// Create a temporary directory for the tests
 	tmpDir, err := os.MkdirTemp("", "samepath_test")
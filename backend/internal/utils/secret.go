@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"dlbackend/internal/config"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ============================================================================
+// SECRETS - Encryption of the secrets stored in the database
+// ============================================================================
+
+// secretKeyFile is the key generated in DataPath when APP_SECRET_KEY is not set.
+const secretKeyFile = "secret.key"
+
+// EncryptSecret encrypts the value with AES-256-GCM, returned as base64.
+func EncryptSecret(value string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts a value encrypted by EncryptSecret.
+func DecryptSecret(encrypted string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid secret: too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	value, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(value), nil
+}
+
+// secretCipher returns the AES-256-GCM cipher of the secret key.
+func secretCipher() (cipher.AEAD, error) {
+	key, err := secretKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretKey returns the 32 bytes key: derived from APP_SECRET_KEY, or read from
+// DataPath, generated on first use. Losing it makes the stored secrets unreadable.
+func secretKey() ([]byte, error) {
+	if config.Cfg.SecretKey != "" {
+		sum := sha256.Sum256([]byte(config.Cfg.SecretKey))
+		return sum[:], nil
+	}
+
+	path := filepath.Join(config.Cfg.DataPath, secretKeyFile)
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid secret key %s: %d bytes instead of 32", path, len(key))
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+
+	// Generate the key in a temp file, then link it: a concurrent caller never reads a
+	// partial key, and the first one to link wins
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	file, err := os.CreateTemp(config.Cfg.DataPath, secretKeyFile+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secret key: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(key)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write secret key: %w", err)
+	}
+	if err := os.Link(file.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return secretKey()
+		}
+		return nil, fmt.Errorf("failed to create secret key: %w", err)
+	}
+	return key, nil
+}
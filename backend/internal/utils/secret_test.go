@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"dlbackend/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	t.Run("generated key", func(t *testing.T) {
+		dataPath := t.TempDir()
+		config.Cfg = &config.Config{DataPath: dataPath}
+
+		encrypted, err := EncryptSecret("p4ssw0rd")
+		if err != nil {
+			t.Fatalf("EncryptSecret() error = %v", err)
+		}
+		if encrypted == "p4ssw0rd" {
+			t.Errorf("EncryptSecret() returned the plain value")
+		}
+
+		key, err := os.ReadFile(filepath.Join(dataPath, secretKeyFile))
+		if err != nil || len(key) != 32 {
+			t.Fatalf("secret key not generated: %v (%d bytes)", err, len(key))
+		}
+
+		// The key is reused by the next calls
+		got, err := DecryptSecret(encrypted)
+		if err != nil || got != "p4ssw0rd" {
+			t.Errorf("DecryptSecret() = %q, %v, want %q", got, err, "p4ssw0rd")
+		}
+	})
+
+	t.Run("key from the configuration", func(t *testing.T) {
+		dataPath := t.TempDir()
+		config.Cfg = &config.Config{DataPath: dataPath, SecretKey: "secret"}
+
+		encrypted, err := EncryptSecret("p4ssw0rd")
+		if err != nil {
+			t.Fatalf("EncryptSecret() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dataPath, secretKeyFile)); !os.IsNotExist(err) {
+			t.Errorf("secret key generated while configured")
+		}
+
+		got, err := DecryptSecret(encrypted)
+		if err != nil || got != "p4ssw0rd" {
+			t.Errorf("DecryptSecret() = %q, %v, want %q", got, err, "p4ssw0rd")
+		}
+
+		// Another key can't decrypt it
+		config.Cfg.SecretKey = "other"
+		if _, err := DecryptSecret(encrypted); err == nil {
+			t.Errorf("DecryptSecret() with another key should fail")
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		config.Cfg = &config.Config{DataPath: t.TempDir(), SecretKey: "secret"}
+
+		for _, value := range []string{"not base64!", "c2hvcnQ="} {
+			if _, err := DecryptSecret(value); err == nil {
+				t.Errorf("DecryptSecret(%q) should fail", value)
+			}
+		}
+	})
+}
@@ -2,21 +2,27 @@ package utils
 
 import (
 	"dlbackend/internal/model"
+	"dlbackend/pkg/client"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // ============================================================================
 // VALIDATION UTILS
 // ============================================================================
 
+// oneFichierFolderPath matches the path of a 1fichier.com shared folder link (/dir/ID)
+var oneFichierFolderPath = regexp.MustCompile(`^/dir/[a-zA-Z0-9_-]+/?$`)
+
 // Validate1FichierURL trim and validate 1fichier.com URL
 //   - cannot be empty
 //   - must be a valid URL
 //   - must begin with https scheme
 //   - must be a valide domain name
-//   - must contains query param
+//   - must contains query param, or be a folder link (/dir/ID)
 func Validate1FichierURL(rawURL string) (string, error) {
 	urlStr := strings.TrimSpace(rawURL)
 
@@ -42,14 +48,66 @@ func Validate1FichierURL(rawURL string) (string, error) {
 		return "", fmt.Errorf("unauthorized domain: %s", parsedURL.Host)
 	}
 
-	// Checks for the presence of query param (1fichier.com file ID)
-	if parsedURL.RawQuery == "" {
+	// Checks for the presence of query param (1fichier.com file ID), unless it's a folder link
+	if parsedURL.RawQuery == "" && !oneFichierFolderPath.MatchString(parsedURL.Path) {
 		return "", fmt.Errorf("1fichier id not found in URL: %s", rawURL)
 	}
 
 	return urlStr, nil
 }
 
+// ValidateDownloadURL trim and validate the URL of a download
+//   - 1fichier.com URL: see Validate1FichierURL
+//   - others: must be a valid http or https URL with a host (direct link)
+func ValidateDownloadURL(rawURL string) (string, error) {
+	if Is1FichierURL(rawURL) {
+		return Validate1FichierURL(rawURL)
+	}
+
+	urlStr := strings.TrimSpace(rawURL)
+	if urlStr == "" {
+		return "", fmt.Errorf("URL is required")
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %s", urlStr)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return "", fmt.Errorf("invalid URL scheme: %s", parsedURL.Scheme)
+	}
+	if parsedURL.Host == "" {
+		return "", fmt.Errorf("invalid URL: %s", urlStr)
+	}
+
+	return urlStr, nil
+}
+
+// Is1FichierURL reports whether the URL is on the 1fichier.com domain.
+func Is1FichierURL(rawURL string) bool {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsedURL.Host)
+	return host == "1fichier.com" || host == "www.1fichier.com"
+}
+
+// Is1FichierFolderURL reports whether a validated URL is a 1fichier.com shared folder link.
+func Is1FichierFolderURL(rawURL string) bool {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	return Is1FichierURL(rawURL) && oneFichierFolderPath.MatchString(parsedURL.Path)
+}
+
+// OneFichierFileID extract the file ID from a 1fichier.com URL (https://1fichier.com/?id&...)
+// The ID is case insensitive and returned lower-cased, so it can be used to compare links.
+func OneFichierFileID(rawURL string) (string, error) {
+	return client.OneFichierFileID(rawURL)
+}
+
 // ValidateType convert string input to DownloadType and validate
 func ValidateType(typeStr string) (model.DownloadType, error) {
 	typeStr = strings.TrimSpace(typeStr)
@@ -62,6 +120,32 @@ func ValidateType(typeStr string) (model.DownloadType, error) {
 	}
 }
 
+// ValidateDownloadSort convert string input to DownloadSort and validate (empty = created date)
+func ValidateDownloadSort(sortStr string) (model.DownloadSort, error) {
+	sortStr = strings.TrimSpace(sortStr)
+	sort := model.DownloadSort(sortStr)
+	switch sort {
+	case "":
+		return model.SortCreatedAt, nil
+	case model.SortCreatedAt, model.SortPriority:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("invalid sort: %s", sortStr)
+	}
+}
+
+// ValidateConflictPolicy convert string input to ConflictPolicy and validate
+func ValidateConflictPolicy(policyStr string) (model.ConflictPolicy, error) {
+	policyStr = strings.TrimSpace(policyStr)
+	policy := model.ConflictPolicy(policyStr)
+	switch policy {
+	case model.ConflictRename, model.ConflictOverwrite, model.ConflictSkip, model.ConflictFail:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy: %s", policyStr)
+	}
+}
+
 // ValidateNotEmpty trim the string value and check if it's empty
 func ValidateNotEmpty(name string, value string) (string, error) {
 	value = strings.TrimSpace(value)
@@ -71,6 +155,38 @@ func ValidateNotEmpty(name string, value string) (string, error) {
 	return value, nil
 }
 
+// ValidateIntRange check if the value is between min and max (inclusive)
+func ValidateIntRange(name string, value int, min int, max int) (int, error) {
+	if value < min || value > max {
+		return 0, fmt.Errorf("'%s' must be between %d and %d", name, min, max)
+	}
+	return value, nil
+}
+
+// ValidateSchedule check the download schedule rules
+//   - days must be between 0 (Sunday) and 6 (Saturday)
+//   - start and end must be HH:MM times of day
+//   - speed limit must be positive or zero
+func ValidateSchedule(rules []model.ScheduleRule) ([]model.ScheduleRule, error) {
+	for i, rule := range rules {
+		for _, day := range rule.Days {
+			if day < time.Sunday || day > time.Saturday {
+				return nil, fmt.Errorf("schedule rule %d: invalid day %d", i, day)
+			}
+		}
+		if _, err := model.ParseClock(rule.Start); err != nil {
+			return nil, fmt.Errorf("schedule rule %d: start: %w", i, err)
+		}
+		if _, err := model.ParseClock(rule.End); err != nil {
+			return nil, fmt.Errorf("schedule rule %d: end: %w", i, err)
+		}
+		if rule.SpeedLimit < 0 {
+			return nil, fmt.Errorf("schedule rule %d: speed limit must be positive or zero", i)
+		}
+	}
+	return rules, nil
+}
+
 // ValidatePath trim and validates path format
 //   - cannot be empty
 //   - cannot contains more then 4096 characters
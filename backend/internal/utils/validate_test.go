@@ -3,6 +3,7 @@ package utils
 import (
 	"dlbackend/internal/model"
 	"testing"
+	"time"
 )
 
 func TestValidate1FichierURL(t *testing.T) {
@@ -65,6 +66,17 @@ func TestValidate1FichierURL(t *testing.T) {
 			input:   "https://api.1fichier.com/?abc",
 			wantErr: true,
 		},
+		{
+			name:    "folder link",
+			input:   "https://1fichier.com/dir/AbC12xyZ",
+			want:    "https://1fichier.com/dir/AbC12xyZ",
+			wantErr: false,
+		},
+		{
+			name:    "folder link without ID",
+			input:   "https://1fichier.com/dir/",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -81,6 +93,37 @@ func TestValidate1FichierURL(t *testing.T) {
 	}
 }
 
+func TestValidateDownloadURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "1fichier link", input: " https://1fichier.com/?abc123 ", want: "https://1fichier.com/?abc123"},
+		{name: "invalid 1fichier link", input: "https://1fichier.com/", wantErr: true},
+		{name: "direct https link", input: "https://example.com/files/movie.mkv", want: "https://example.com/files/movie.mkv"},
+		{name: "direct http link", input: "http://192.168.1.10:8080/movie.mkv", want: "http://192.168.1.10:8080/movie.mkv"},
+		{name: "empty URL", input: "  ", wantErr: true},
+		{name: "unsupported scheme", input: "ftp://example.com/movie.mkv", wantErr: true},
+		{name: "missing host", input: "https:///movie.mkv", wantErr: true},
+		{name: "relative URL", input: "movie.mkv", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateDownloadURL(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDownloadURL() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ValidateDownloadURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateType(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -137,6 +180,102 @@ func TestValidateType(t *testing.T) {
 	}
 }
 
+func TestValidateDownloadSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    model.DownloadSort
+		wantErr bool
+	}{
+		{
+			name:  "default",
+			input: "",
+			want:  model.SortCreatedAt,
+		},
+		{
+			name:  "created date",
+			input: "createdAt",
+			want:  model.SortCreatedAt,
+		},
+		{
+			name:  "priority with whitespace",
+			input: " priority ",
+			want:  model.SortPriority,
+		},
+		{
+			name:    "invalid sort",
+			input:   "size",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateDownloadSort(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDownloadSort() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ValidateDownloadSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConflictPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    model.ConflictPolicy
+		wantErr bool
+	}{
+		{
+			name:  "rename",
+			input: "RENAME",
+			want:  model.ConflictRename,
+		},
+		{
+			name:  "overwrite with whitespace",
+			input: " OVERWRITE ",
+			want:  model.ConflictOverwrite,
+		},
+		{
+			name:  "skip",
+			input: "SKIP",
+			want:  model.ConflictSkip,
+		},
+		{
+			name:  "fail",
+			input: "FAIL",
+			want:  model.ConflictFail,
+		},
+		{
+			name:    "invalid policy",
+			input:   "replace",
+			wantErr: true,
+		},
+		{
+			name:    "empty string",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateConflictPolicy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConflictPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ValidateConflictPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateNotEmpty(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -187,6 +326,57 @@ func TestValidateNotEmpty(t *testing.T) {
 	}
 }
 
+func TestValidateIntRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   int
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "value inside range",
+			input:   3,
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "lower bound",
+			input:   1,
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "upper bound",
+			input:   10,
+			want:    10,
+			wantErr: false,
+		},
+		{
+			name:    "below range",
+			input:   0,
+			wantErr: true,
+		},
+		{
+			name:    "above range",
+			input:   11,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateIntRange("value", tt.input, 1, 10)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateIntRange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ValidateIntRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidatePath(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -477,3 +667,121 @@ func TestValidateSegment(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []model.ScheduleRule
+		wantErr bool
+	}{
+		{
+			name:    "no schedule",
+			input:   nil,
+			wantErr: false,
+		},
+		{
+			name: "valid rules",
+			input: []model.ScheduleRule{
+				{Days: []time.Weekday{time.Monday, time.Friday}, Start: "08:00", End: "18:30", Paused: true},
+				{Start: "22:00", End: "06:00", SpeedLimit: 1024},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid day",
+			input:   []model.ScheduleRule{{Days: []time.Weekday{7}, Start: "08:00", End: "18:00"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid start",
+			input:   []model.ScheduleRule{{Start: "8h", End: "18:00"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid end",
+			input:   []model.ScheduleRule{{Start: "08:00", End: "25:00"}},
+			wantErr: true,
+		},
+		{
+			name:    "negative speed limit",
+			input:   []model.ScheduleRule{{Start: "08:00", End: "18:00", SpeedLimit: -1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateSchedule(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOneFichierFileID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "simple link",
+			input:   "https://1fichier.com/?abc123",
+			want:    "abc123",
+			wantErr: false,
+		},
+		{
+			name:    "extra query params and uppercase",
+			input:   "https://www.1fichier.com/?ABC123&af=42",
+			want:    "abc123",
+			wantErr: false,
+		},
+		{
+			name:    "no file ID",
+			input:   "https://1fichier.com/",
+			wantErr: true,
+		},
+		{
+			name:    "key=value query",
+			input:   "https://1fichier.com/?id=abc123",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OneFichierFileID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("OneFichierFileID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("OneFichierFileID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIs1FichierFolderURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "folder link", input: "https://1fichier.com/dir/AbC12xyZ", want: true},
+		{name: "folder link with trailing slash", input: "https://www.1fichier.com/dir/AbC12xyZ/", want: true},
+		{name: "file link", input: "https://1fichier.com/?abc123", want: false},
+		{name: "nested path", input: "https://1fichier.com/dir/abc/def", want: false},
+		{name: "folder path on another domain", input: "https://example.com/dir/AbC12xyZ", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Is1FichierFolderURL(tt.input); got != tt.want {
+				t.Errorf("Is1FichierFolderURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
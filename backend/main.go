@@ -9,6 +9,7 @@ import (
 	"dlbackend/internal/route"
 	"dlbackend/internal/utils"
 	"dlbackend/pkg/sse"
+	"dlbackend/pkg/worker"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -69,6 +70,12 @@ func main() {
 	// Initialize routes
 	route.SetupRoutes(app, container)
 
+	// Restore downloads interrupted by the previous shutdown, then start the queue scheduler
+	if err := container.DownloadManager.Restore(); err != nil {
+		log.Errorf("Failed to restore downloads: %v", err)
+	}
+	go container.DownloadManager.Run()
+
 	// Start server in goroutine
 	go func() {
 		log.Infof("Version: %s", Version)
@@ -79,10 +86,10 @@ func main() {
 	}()
 
 	// Wait for server shutdown signal
-	waitForShutdown(app)
+	waitForShutdown(app, container.DownloadManager)
 }
 
-func waitForShutdown(app *fiber.App) {
+func waitForShutdown(app *fiber.App, downloadManager *worker.DownloadManager) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
@@ -96,5 +103,8 @@ func waitForShutdown(app *fiber.App) {
 		log.Warnf("Error during shutdown: %v", err)
 	}
 
+	// Persist the buffered download progress before the database is closed
+	downloadManager.Close()
+
 	log.Info("Server shutdown complete.")
 }
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// directProvider is the Provider of plain HTTP(S) links: the link is the file itself,
+// its metadata comes from the response headers.
+type directProvider struct {
+	headClient   *http.Client // with timeout for metadata requests
+	httpClient   *http.Client // no timeout for file streaming
+	allowPrivate bool         // Private network addresses are reachable
+}
+
+// ErrPrivateAddress is returned when a direct link leads to a loopback, link-local or
+// private network address, unless they are allowed.
+var ErrPrivateAddress = errors.New("private network address not allowed")
+
+// NewDirectProvider returns the provider of the plain HTTP(S) links. Unless allowPrivate,
+// links to loopback, link-local and private network addresses are rejected: by Match,
+// and by the dialer for the addresses resolved on connection (e.g. after a redirect).
+func NewDirectProvider(allowPrivate bool) Provider {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+	}
+	return &directProvider{
+		headClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: transport,
+		},
+		httpClient: &http.Client{
+			Timeout:   0, // no timeout for body streaming
+			Transport: transport,
+		},
+		allowPrivate: allowPrivate,
+	}
+}
+
+func (p *directProvider) Name() string {
+	return ProviderDirect
+}
+
+// Match reports whether the link is an absolute HTTP(S) URL. Unless allowed, the host
+// must not resolve to a private network address; a host that can't be resolved is left
+// to the dialer.
+func (p *directProvider) Match(fileURL string) bool {
+	parsedURL, err := url.Parse(strings.TrimSpace(fileURL))
+	if err != nil {
+		return false
+	}
+	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return false
+	}
+	if p.allowPrivate {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsedURL.Hostname())
+	if err != nil {
+		return true
+	}
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPrivateIP reports whether the address is not reachable from the internet: loopback,
+// unspecified (reaches the local host), link-local (e.g. cloud metadata) or private.
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsPrivate()
+}
+
+// GetFileInfo reads the metadata from the headers of a HEAD request, or of the first
+// byte when the server doesn't answer HEAD requests. Direct links have no password.
+func (p *directProvider) GetFileInfo(ctx context.Context, fileURL string, password string) (*FileInfo, error) {
+	if password != "" {
+		return nil, errors.New("direct links are not password protected")
+	}
+
+	resp, err := p.probe(ctx, fileURL, "HEAD")
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = p.probe(ctx, fileURL, "GET")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("failed to get file info: %w (status %d)", ErrFileNotFound, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent:
+		return nil, fmt.Errorf("failed to get file info: %w", &DownloadStatusError{StatusCode: resp.StatusCode})
+	}
+
+	return &FileInfo{
+		Filename:    directFileName(resp),
+		Size:        directFileSize(resp),
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// ResolveDownloadURL returns the link itself, it doesn't expire.
+func (p *directProvider) ResolveDownloadURL(ctx context.Context, fileURL string, password string) (*DownloadLink, error) {
+	return &DownloadLink{URL: fileURL}, nil
+}
+
+func (p *directProvider) DownloadFile(ctx context.Context, downloadURL string, offset int64) (io.ReadCloser, int64, int, error) {
+	return openStream(ctx, p.httpClient, downloadURL, offsetRange(offset))
+}
+
+func (p *directProvider) DownloadRange(ctx context.Context, downloadURL string, start int64, end int64) (io.ReadCloser, int64, int, error) {
+	return openStream(ctx, p.httpClient, downloadURL, fmt.Sprintf("bytes=%d-%d", start, end))
+}
+
+// probe sends a metadata request: a HEAD, or a GET of the first byte. The body is discarded.
+func (p *directProvider) probe(ctx context.Context, fileURL string, method string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if method == "GET" {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := p.headClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// directFileName returns the file name from the Content-Disposition header, or the last
+// segment of the final URL (after redirects), "download" if neither is a valid name.
+func directFileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := strings.TrimSpace(params["filename"]); validFileName(name) {
+			return name
+		}
+	}
+	if name := path.Base(resp.Request.URL.Path); validFileName(name) {
+		return name
+	}
+	return "download"
+}
+
+// validFileName reports whether a name announced by the server can be used in the
+// download directory: not empty, not "." or "..", without path separator or control character.
+func validFileName(name string) bool {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return false
+	}
+	for _, r := range name {
+		if r < 32 || r == 127 {
+			return false
+		}
+	}
+	return true
+}
+
+// directFileSize returns the file size: the total of the Content-Range header for a
+// ranged answer, the Content-Length otherwise, 0 if unknown.
+func directFileSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		_, total, _ := strings.Cut(resp.Header.Get("Content-Range"), "/")
+		size, err := strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			return 0
+		}
+		return size
+	}
+	return max(resp.ContentLength, 0)
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		disposition string
+		path        string
+		want        string
+	}{
+		{name: "Content-Disposition", disposition: `attachment; filename="movie.mkv"`, path: "/get/123", want: "movie.mkv"},
+		{name: "encoded Content-Disposition", disposition: `attachment; filename*=UTF-8''caf%C3%A9.txt`, path: "/get/123", want: "café.txt"},
+		{name: "URL path", path: "/files/movie.mkv", want: "movie.mkv"},
+		{name: "escaped URL path", path: "/files/my%20movie.mkv", want: "my movie.mkv"},
+		{name: "parent in Content-Disposition", disposition: `attachment; filename=".."`, path: "/files/movie.mkv", want: "movie.mkv"},
+		{name: "path in Content-Disposition", disposition: `attachment; filename="../../etc/passwd"`, path: "/files/movie.mkv", want: "movie.mkv"},
+		{name: "Windows path in Content-Disposition", disposition: `attachment; filename="..\\..\\evil.exe"`, path: "/files/movie.mkv", want: "movie.mkv"},
+		{name: "empty Content-Disposition name", disposition: `attachment; filename=""`, path: "/files/movie.mkv", want: "movie.mkv"},
+		{name: "blank Content-Disposition name", disposition: `attachment; filename="  "`, path: "/files/movie.mkv", want: "movie.mkv"},
+		{name: "invalid Content-Disposition", disposition: `attachment; filename`, path: "/files/movie.mkv", want: "movie.mkv"},
+		{name: "parent in URL path", path: "/files/%2e%2e", want: "download"},
+		{name: "backslash in URL path", path: "/files/..%5Cevil.exe", want: "download"},
+		{name: "root", path: "/", want: "download"},
+		{name: "no path", path: "", want: "download"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestURL, err := url.Parse("https://example.com" + tt.path)
+			require.NoError(t, err)
+			resp := &http.Response{
+				Header:  http.Header{},
+				Request: &http.Request{URL: requestURL},
+			}
+			if tt.disposition != "" {
+				resp.Header.Set("Content-Disposition", tt.disposition)
+			}
+
+			assert.Equal(t, tt.want, directFileName(resp))
+		})
+	}
+}
+
+func TestDirectProvider_GetFileInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantName string
+		wantSize int64
+		wantErr  error
+	}{
+		{
+			name: "HEAD",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Disposition", `attachment; filename=".."`)
+				w.Header().Set("Content-Length", "42")
+			},
+			wantName: "file.bin",
+			wantSize: 42,
+		},
+		{
+			name: "GET fallback",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "HEAD" {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				assert.Equal(t, "bytes=0-0", r.Header.Get("Range"))
+				w.Header().Set("Content-Range", "bytes 0-0/1000")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte{0})
+			},
+			wantName: "file.bin",
+			wantSize: 1000,
+		},
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantErr: ErrFileNotFound,
+		},
+		{
+			name: "forbidden",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			wantErr: ErrLinkExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			info, err := NewDirectProvider(true).GetFileInfo(context.Background(), server.URL+"/files/file.bin", "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, info.Filename)
+			assert.Equal(t, tt.wantSize, info.Size)
+		})
+	}
+}
+
+func TestDirectProvider_PrivateAddresses(t *testing.T) {
+	tests := []struct {
+		fileURL string
+		private bool
+	}{
+		{fileURL: "http://127.0.0.1/file.bin", private: true},
+		{fileURL: "http://localhost:8080/file.bin", private: true},
+		{fileURL: "http://[::1]/file.bin", private: true},
+		{fileURL: "http://0.0.0.0/file.bin", private: true},
+		{fileURL: "http://169.254.169.254/latest/meta-data", private: true},
+		{fileURL: "http://10.0.0.1/file.bin", private: true},
+		{fileURL: "http://192.168.1.20/file.bin", private: true},
+		{fileURL: "https://[fd00::1]/file.bin", private: true},
+		{fileURL: "https://93.184.215.14/file.bin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileURL, func(t *testing.T) {
+			assert.Equal(t, !tt.private, NewDirectProvider(false).Match(tt.fileURL))
+			assert.True(t, NewDirectProvider(true).Match(tt.fileURL))
+		})
+	}
+
+	t.Run("dialer rejects private addresses", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("the server must not be reached")
+		}))
+		defer server.Close()
+
+		provider := NewDirectProvider(false)
+		_, err := provider.GetFileInfo(context.Background(), server.URL+"/files/file.bin", "")
+		assert.ErrorIs(t, err, ErrPrivateAddress)
+		_, _, _, err = provider.DownloadFile(context.Background(), server.URL+"/files/file.bin", 0)
+		assert.ErrorIs(t, err, ErrPrivateAddress)
+	})
+}
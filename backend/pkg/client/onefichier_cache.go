@@ -0,0 +1,98 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ===============================
+// File info cache
+// ===============================
+
+const (
+	// fileInfoCacheTTL is the time a file info is reused without calling the API
+	fileInfoCacheTTL = 10 * time.Minute
+	// fileInfoCacheSize is the maximum number of cached file infos
+	fileInfoCacheSize = 500
+)
+
+// fileInfos caches the /file/info.cgi answers of all the clients: the same file is
+// looked up when its link is pasted, when the download is created and when it starts.
+var fileInfos = newFileInfoCache(fileInfoCacheTTL, fileInfoCacheSize)
+
+// fileInfoCache is an LRU cache of file infos by file ID, with an expiry.
+type fileInfoCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*list.Element // File ID -> element of order
+	order   *list.List               // *fileInfoEntry, most recently used first
+}
+
+type fileInfoEntry struct {
+	fileID    string
+	info      OneFichierInfoResponse
+	expiresAt time.Time
+}
+
+func newFileInfoCache(ttl time.Duration, size int) *fileInfoCache {
+	return &fileInfoCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of the file info, if cached and not expired.
+func (c *fileInfoCache) get(fileID string) (*OneFichierInfoResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[fileID]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*fileInfoEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, fileID)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	info := entry.info
+	return &info, true
+}
+
+// put caches a copy of the file info, evicting the least recently used one when full.
+func (c *fileInfoCache) put(fileID string, info *OneFichierInfoResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &fileInfoEntry{fileID: fileID, info: *info, expiresAt: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[fileID]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[fileID] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*fileInfoEntry).fileID)
+	}
+}
+
+// remove drops the file info, e.g. when the file was deleted.
+func (c *fileInfoCache) remove(fileID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[fileID]; ok {
+		c.order.Remove(element)
+		delete(c.entries, fileID)
+	}
+}
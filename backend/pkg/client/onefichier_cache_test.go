@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileInfoCache(t *testing.T) {
+	info := func(name string) *OneFichierInfoResponse {
+		return &OneFichierInfoResponse{Filename: name}
+	}
+
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		size  int
+		run   func(c *fileInfoCache)
+		found []string // File IDs still cached
+		gone  []string // File IDs no longer cached
+	}{
+		{
+			name: "evicts the least recently put",
+			ttl:  time.Minute,
+			size: 2,
+			run: func(c *fileInfoCache) {
+				c.put("a", info("a"))
+				c.put("b", info("b"))
+				c.put("c", info("c"))
+			},
+			found: []string{"b", "c"},
+			gone:  []string{"a"},
+		},
+		{
+			name: "a get keeps the entry",
+			ttl:  time.Minute,
+			size: 2,
+			run: func(c *fileInfoCache) {
+				c.put("a", info("a"))
+				c.put("b", info("b"))
+				c.get("a")
+				c.put("c", info("c"))
+			},
+			found: []string{"a", "c"},
+			gone:  []string{"b"},
+		},
+		{
+			name: "a put of a cached file doesn't evict",
+			ttl:  time.Minute,
+			size: 2,
+			run: func(c *fileInfoCache) {
+				c.put("a", info("a"))
+				c.put("b", info("b"))
+				c.put("a", info("a2"))
+			},
+			found: []string{"a", "b"},
+		},
+		{
+			name: "expires after the TTL",
+			ttl:  10 * time.Millisecond,
+			size: 2,
+			run: func(c *fileInfoCache) {
+				c.put("a", info("a"))
+				time.Sleep(20 * time.Millisecond)
+			},
+			gone: []string{"a"},
+		},
+		{
+			name: "remove",
+			ttl:  time.Minute,
+			size: 2,
+			run: func(c *fileInfoCache) {
+				c.put("a", info("a"))
+				c.put("b", info("b"))
+				c.remove("a")
+				c.remove("unknown")
+			},
+			found: []string{"b"},
+			gone:  []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFileInfoCache(tt.ttl, tt.size)
+			tt.run(c)
+
+			for _, fileID := range tt.found {
+				_, ok := c.get(fileID)
+				assert.True(t, ok, "%s should be cached", fileID)
+			}
+			for _, fileID := range tt.gone {
+				_, ok := c.get(fileID)
+				assert.False(t, ok, "%s should not be cached", fileID)
+			}
+			assert.Equal(t, len(tt.found), c.order.Len())
+			assert.Len(t, c.entries, len(tt.found))
+		})
+	}
+
+	t.Run("returns copies", func(t *testing.T) {
+		c := newFileInfoCache(time.Minute, 2)
+		original := info("movie.mkv")
+		c.put("a", original)
+
+		original.Filename = "changed after put"
+		got, ok := c.get("a")
+		require.True(t, ok)
+		assert.Equal(t, "movie.mkv", got.Filename)
+
+		got.Filename = "changed after get"
+		got, ok = c.get("a")
+		require.True(t, ok)
+		assert.Equal(t, "movie.mkv", got.Filename)
+	})
+}
+
+func TestOneFichierClient_FileInfoCache(t *testing.T) {
+	// infoAPI is a fake 1fichier API counting the info lookups
+	infoAPI := func(pass int, tokenMessage string) (http.HandlerFunc, *atomic.Int32) {
+		var lookups atomic.Int32
+		return func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/file/info.cgi":
+				lookups.Add(1)
+				answer(http.StatusOK, map[string]any{"filename": "movie.mkv", "size": 42, "pass": pass})(w, r)
+			case "/download/get_token.cgi":
+				answer(http.StatusForbidden, map[string]string{"status": "KO", "message": tokenMessage})(w, r)
+			}
+		}, &lookups
+	}
+
+	t.Run("reuses the info", func(t *testing.T) {
+		handler, lookups := infoAPI(0, "")
+		client, _ := newTestAPI(t, handler)
+
+		for range 2 {
+			info, err := client.GetFileInfo(context.Background(), "https://1fichier.com/?cachereuse", "")
+			require.NoError(t, err)
+			assert.Equal(t, "movie.mkv", info.Filename)
+		}
+		// The same file with another link form
+		_, err := client.GetFileInfo(context.Background(), "https://1fichier.com/?cachereuse&af=123", "")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), lookups.Load())
+	})
+
+	t.Run("password protected file not cached", func(t *testing.T) {
+		handler, lookups := infoAPI(1, "")
+		client, _ := newTestAPI(t, handler)
+
+		for range 2 {
+			_, err := client.GetFileInfo(context.Background(), "https://1fichier.com/?cachepass", "secret")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), lookups.Load())
+	})
+
+	t.Run("deleted file found by the token request", func(t *testing.T) {
+		handler, lookups := infoAPI(0, "Resource not found #469")
+		client, _ := newTestAPI(t, handler)
+
+		_, err := client.GetFileInfo(context.Background(), "https://1fichier.com/?cachedeleted", "")
+		require.NoError(t, err)
+		_, err = client.GetDownloadToken(context.Background(), "https://1fichier.com/?cachedeleted", "")
+		require.ErrorIs(t, err, ErrFileNotFound)
+
+		_, err = client.GetFileInfo(context.Background(), "https://1fichier.com/?cachedeleted", "")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), lookups.Load())
+	})
+
+	t.Run("other token errors keep the info", func(t *testing.T) {
+		handler, lookups := infoAPI(0, "Not authenticated #247")
+		client, _ := newTestAPI(t, handler)
+
+		_, err := client.GetFileInfo(context.Background(), "https://1fichier.com/?cachekept", "")
+		require.NoError(t, err)
+		_, err = client.GetDownloadToken(context.Background(), "https://1fichier.com/?cachekept", "")
+		require.ErrorIs(t, err, ErrUnauthorized)
+
+		_, err = client.GetFileInfo(context.Background(), "https://1fichier.com/?cachekept", "")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), lookups.Load())
+	})
+}
@@ -2,10 +2,19 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"dlbackend/internal/config"
+	"dlbackend/pkg/ratelimit"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,9 +22,11 @@ import (
 // Client Interface
 // ===============================
 type OneFichierClient interface {
-	GetFileInfo(fileURL string) (*OneFichierInfoResponse, error)
-	GetDownloadToken(fileURL string) (*OneFichierTokenResponse, error)
-	DownloadFile(downloadURL string, offset int64) (io.ReadCloser, int64, int, error)
+	GetFileInfo(ctx context.Context, fileURL string, password string) (*OneFichierInfoResponse, error)
+	GetDownloadToken(ctx context.Context, fileURL string, password string) (*OneFichierTokenResponse, error)
+	DownloadFile(ctx context.Context, downloadURL string, offset int64) (io.ReadCloser, int64, int, error)
+	DownloadRange(ctx context.Context, downloadURL string, start int64, end int64) (io.ReadCloser, int64, int, error)
+	ListFolder(ctx context.Context, folderURL string) ([]OneFichierFolderEntry, error)
 }
 
 // ===============================
@@ -26,6 +37,7 @@ type oneFichierClient struct {
 	apiKey     string
 	apiClient  *http.Client // with timeout for short API calls
 	httpClient *http.Client // no timeout for file streaming
+	limiter    *ratelimit.RequestLimiter
 }
 
 // ===============================
@@ -41,7 +53,7 @@ type OneFichierInfoResponse struct {
 	Checksum    string  `json:"checksum"`
 	ContentType string  `json:"content-type"`
 	Description *string `json:"description,omitempty"`
-	Pass        int     `json:"pass"`
+	Pass        int     `json:"pass"` // 1 if the file is password protected
 	Path        string  `json:"path"`
 	FolderID    string  `json:"folder_id"`
 	Status      *string `json:"status,omitempty"`
@@ -55,6 +67,110 @@ type OneFichierTokenResponse struct {
 	Message *string `json:"message,omitempty"`
 }
 
+// OneFichierFolderEntry file of a shared folder listing (/dir/ID?json=1)
+type OneFichierFolderEntry struct {
+	Link        string `json:"link"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content-type"`
+}
+
+// ===============================
+// Errors
+// ===============================
+
+// ErrLinkExpired is matched by errors returned by DownloadFile when the
+// download URL is no longer valid and a new token must be requested.
+var ErrLinkExpired = errors.New("download link expired")
+
+// Errors matched by the errors returned by the API calls, parsed from the status
+// code and the message of 1fichier.
+var (
+	ErrUnauthorized     = errors.New("API key rejected")
+	ErrFileNotFound     = errors.New("file not found")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrPasswordRequired = errors.New("password required")
+	ErrInvalidPassword  = errors.New("invalid password")
+)
+
+// APIError is returned by the API calls when 1fichier answers with an error.
+// It wraps the matching error above, if any.
+type APIError struct {
+	StatusCode int
+	Message    string
+	err        error
+}
+
+// NewAPIError returns the error of a 1fichier answer, classified from its status code and message.
+func NewAPIError(statusCode int, message string) *APIError {
+	return &APIError{StatusCode: statusCode, Message: message, err: classifyAPIError(statusCode, message)}
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("1fichier API error (status %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("1fichier API error (status %d): %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// classifyAPIError maps a 1fichier error to one of the errors above, nil if unknown.
+// 1fichier answers most errors with a 403 and a message like "Resource not found #469",
+// so the message is checked first.
+func classifyAPIError(statusCode int, message string) error {
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "password"):
+		if strings.Contains(message, "invalid") || strings.Contains(message, "wrong") || strings.Contains(message, "bad") {
+			return ErrInvalidPassword
+		}
+		return ErrPasswordRequired
+	case strings.Contains(message, "flood"), strings.Contains(message, "too many"), strings.Contains(message, "locked"):
+		return ErrRateLimited
+	case strings.Contains(message, "not found"), strings.Contains(message, "deleted"), strings.Contains(message, "removed"):
+		return ErrFileNotFound
+	case strings.Contains(message, "auth"), strings.Contains(message, "api key"), strings.Contains(message, "apikey"):
+		return ErrUnauthorized
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound, http.StatusGone:
+		return ErrFileNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return nil
+	}
+}
+
+// DownloadStatusError is returned by DownloadFile when the server answers
+// with an unexpected status code.
+type DownloadStatusError struct {
+	StatusCode int
+}
+
+func (e *DownloadStatusError) Error() string {
+	return fmt.Sprintf("download failed with status %d", e.StatusCode)
+}
+
+// Is reports 403 (Forbidden) and 410 (Gone) responses as ErrLinkExpired,
+// and 429 (Too Many Requests) as ErrRateLimited.
+func (e *DownloadStatusError) Is(target error) bool {
+	switch target {
+	case ErrLinkExpired:
+		return e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusGone
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
 // ===============================
 // Client Constructor
 // ===============================
@@ -75,101 +191,347 @@ func NewOneFichierClient(baseURL string, apiKey string) OneFichierClient {
 			Timeout:   0, // no timeout for body streaming
 			Transport: transport,
 		},
+		limiter: apiLimiter(apiKey),
 	}
 }
 
-// ===============================
-// POST /file/info.cgi
-// ===============================
-func (c *oneFichierClient) GetFileInfo(fileURL string) (*OneFichierInfoResponse, error) {
-	payload := map[string]string{"url": fileURL}
-	body, err := json.Marshal(payload)
+// dynamicOneFichierClient is the client of the API key of the moment, which can be
+// changed in the settings: the client is built again when the key changes.
+type dynamicOneFichierClient struct {
+	baseURL string
+	apiKey  func() (string, error)
+
+	mu     sync.Mutex
+	key    string
+	client OneFichierClient
+}
+
+// NewDynamicOneFichierClient returns a client reading the API key with apiKey before
+// each call, so that it can be built once at startup.
+func NewDynamicOneFichierClient(baseURL string, apiKey func() (string, error)) OneFichierClient {
+	return &dynamicOneFichierClient{baseURL: baseURL, apiKey: apiKey}
+}
+
+// current returns the client of the current API key.
+func (c *dynamicOneFichierClient) current() (OneFichierClient, error) {
+	key, err := c.apiKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%w: API key not configured", ErrUnauthorized)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil || key != c.key {
+		c.key = key
+		c.client = NewOneFichierClient(c.baseURL, key)
+	}
+	return c.client, nil
+}
+
+func (c *dynamicOneFichierClient) GetFileInfo(ctx context.Context, fileURL string, password string) (*OneFichierInfoResponse, error) {
+	client, err := c.current()
 	if err != nil {
 		return nil, err
 	}
+	return client.GetFileInfo(ctx, fileURL, password)
+}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/file/info.cgi", bytes.NewBuffer(body))
+func (c *dynamicOneFichierClient) GetDownloadToken(ctx context.Context, fileURL string, password string) (*OneFichierTokenResponse, error) {
+	client, err := c.current()
 	if err != nil {
 		return nil, err
 	}
+	return client.GetDownloadToken(ctx, fileURL, password)
+}
 
-	c.setHeaders(req)
-	resp, err := c.apiClient.Do(req)
+func (c *dynamicOneFichierClient) DownloadFile(ctx context.Context, downloadURL string, offset int64) (io.ReadCloser, int64, int, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return client.DownloadFile(ctx, downloadURL, offset)
+}
+
+func (c *dynamicOneFichierClient) DownloadRange(ctx context.Context, downloadURL string, start int64, end int64) (io.ReadCloser, int64, int, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return client.DownloadRange(ctx, downloadURL, start, end)
+}
+
+func (c *dynamicOneFichierClient) ListFolder(ctx context.Context, folderURL string) ([]OneFichierFolderEntry, error) {
+	client, err := c.current()
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return client.ListFolder(ctx, folderURL)
+}
+
+// ===============================
+// Flood protection
+// ===============================
+
+// maxFloodRetries is the number of times a call is sent again after a flood answer,
+// before the error is returned.
+const maxFloodRetries = 3
+
+// defaultFloodDelay is the pause after a flood answer without wait hint.
+const defaultFloodDelay = time.Minute
+
+// apiLimiters holds the request limiter of each account, shared by all the clients
+// using its API key: the budget is per account, whatever the number of clients.
+var (
+	apiLimiters   = make(map[string]*ratelimit.RequestLimiter)
+	apiLimitersMu sync.Mutex
+)
+
+func apiLimiter(apiKey string) *ratelimit.RequestLimiter {
+	apiLimitersMu.Lock()
+	defer apiLimitersMu.Unlock()
+
+	limiter, ok := apiLimiters[apiKey]
+	if !ok {
+		limiter = ratelimit.NewRequestLimiter(config.Cfg.ApiRateLimit1fichier, time.Minute)
+		apiLimiters[apiKey] = limiter
+	}
+	return limiter
+}
+
+// waitHintPattern matches a wait asked in a flood message, e.g. "retry in 30 seconds".
+var waitHintPattern = regexp.MustCompile(`(?i)(\d+)\s*(seconds?|secs?|s|minutes?|mins?|m|hours?|h)\b`)
+
+// floodDelay returns the pause asked by a flood answer: the Retry-After header, the
+// wait in the message, or defaultFloodDelay. ok is false if the answer is not a flood.
+func floodDelay(resp *http.Response, body []byte) (delay time.Duration, ok bool) {
+	var answer struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &answer) // Not every answer is an object, the status code is enough
+	if classifyAPIError(resp.StatusCode, answer.Message) != ErrRateLimited {
+		return 0, false
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil && time.Until(date) > 0 {
+			return time.Until(date), true
+		}
+	}
+
+	if match := waitHintPattern.FindStringSubmatch(answer.Message); match != nil {
+		value, _ := strconv.Atoi(match[1])
+		unit := time.Second
+		switch strings.ToLower(match[2])[0] {
+		case 'm':
+			unit = time.Minute
+		case 'h':
+			unit = time.Hour
+		}
+		if value > 0 {
+			return time.Duration(value) * unit, true
+		}
+	}
+
+	return defaultFloodDelay, true
+}
+
+// ===============================
+// POST /file/info.cgi
+// ===============================
+
+// GetFileInfo returns the file info, from the cache when it was looked up recently.
+// The info of a password protected file is not cached: it would be returned
+// without checking the password.
+func (c *oneFichierClient) GetFileInfo(ctx context.Context, fileURL string, password string) (*OneFichierInfoResponse, error) {
+	fileID, idErr := OneFichierFileID(fileURL)
+	if idErr == nil {
+		if info, ok := fileInfos.get(fileID); ok {
+			return info, nil
+		}
+	}
 
 	var result OneFichierInfoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	statusCode, err := c.post(ctx, "/file/info.cgi", filePayload(fileURL, password), &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	if resp.StatusCode == 403 || result.Status != nil && *result.Status == "KO" {
-		return nil, fmt.Errorf("403 failed to get fileinfo: %v", result.Message)
+	if statusCode != http.StatusOK || result.Status != nil && *result.Status == "KO" {
+		return nil, fmt.Errorf("failed to get file info: %w", NewAPIError(statusCode, messageOf(result.Message)))
 	}
 
+	if idErr == nil && result.Pass == 0 {
+		fileInfos.put(fileID, &result)
+	}
 	return &result, nil
 }
 
 // ===============================
 // POST /download/get_token.cgi
 // ===============================
-func (c *oneFichierClient) GetDownloadToken(fileURL string) (*OneFichierTokenResponse, error) {
-	payload := map[string]string{"url": fileURL}
-	body, err := json.Marshal(payload)
+func (c *oneFichierClient) GetDownloadToken(ctx context.Context, fileURL string, password string) (*OneFichierTokenResponse, error) {
+	var result OneFichierTokenResponse
+	statusCode, err := c.post(ctx, "/download/get_token.cgi", filePayload(fileURL, password), &result)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	if result.Status != "OK" {
+		apiErr := NewAPIError(statusCode, messageOf(result.Message))
+		if fileID, err := OneFichierFileID(fileURL); err == nil && errors.Is(apiErr, ErrFileNotFound) {
+			fileInfos.remove(fileID) // The file was deleted since its info was cached
+		}
+		return nil, fmt.Errorf("failed to get token: %w", apiErr)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/download/get_token.cgi", bytes.NewBuffer(body))
+	return &result, nil
+}
+
+// ===============================
+// GET /dir/ID?json=1 (public shared folder listing)
+// ===============================
+func (c *oneFichierClient) ListFolder(ctx context.Context, folderURL string) ([]OneFichierFolderEntry, error) {
+	parsedURL, err := url.Parse(folderURL)
 	if err != nil {
 		return nil, err
 	}
+	query := parsedURL.Query()
+	query.Set("json", "1")
+	parsedURL.RawQuery = query.Encode()
 
-	c.setHeaders(req)
-	resp, err := c.apiClient.Do(req)
+	statusCode, body, err := c.call(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", parsedURL.String(), nil)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var result OneFichierTokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list folder: %w", NewAPIError(statusCode, ""))
 	}
 
-	if result.Status != "OK" {
-		return nil, fmt.Errorf("failed to get token: %v", result.Message)
+	var result []OneFichierFolderEntry
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to list folder: %w", err)
 	}
 
-	return &result, nil
+	return result, nil
 }
 
 // ===============================
 // GET download the file
 // ===============================
-func (c *oneFichierClient) DownloadFile(downloadURL string, offset int64) (io.ReadCloser, int64, int, error) {
-	req, err := http.NewRequest("GET", downloadURL, nil)
+func (c *oneFichierClient) DownloadFile(ctx context.Context, downloadURL string, offset int64) (io.ReadCloser, int64, int, error) {
+	return openStream(ctx, c.httpClient, downloadURL, offsetRange(offset))
+}
+
+// ===============================
+// GET download a byte range of the file (end is inclusive)
+// ===============================
+func (c *oneFichierClient) DownloadRange(ctx context.Context, downloadURL string, start int64, end int64) (io.ReadCloser, int64, int, error) {
+	return openStream(ctx, c.httpClient, downloadURL, fmt.Sprintf("bytes=%d-%d", start, end))
+}
+
+// post sends the payload as JSON to the API endpoint and decodes the JSON answer into result,
+// whatever the status code: 1fichier explains its errors in the body.
+func (c *oneFichierClient) post(ctx context.Context, path string, payload any, result any) (int, error) {
+	body, err := json.Marshal(payload)
 	if err != nil {
-		return nil, 0, 0, err
+		return 0, err
 	}
 
-	if offset > 0 {
-		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	statusCode, answer, err := c.call(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(req)
+		return req, nil
+	})
+	if err != nil {
+		return statusCode, err
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, 0, 0, err
+	if err := json.Unmarshal(answer, result); err != nil {
+		if statusCode != http.StatusOK {
+			return statusCode, NewAPIError(statusCode, "")
+		}
+		return statusCode, err
 	}
+	return statusCode, nil
+}
+
+// call sends the request built by newRequest once the account limiter allows it, and
+// returns the status code and body of the answer. A flood answer pauses the limiter of
+// the account for the wait asked by 1fichier, then the request is queued again, up to
+// maxFloodRetries times.
+func (c *oneFichierClient) call(ctx context.Context, newRequest func() (*http.Request, error)) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return 0, nil, err
+		}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		req, err := newRequest()
+		if err != nil {
+			return 0, nil, err
+		}
+		resp, err := c.apiClient.Do(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, 0, 0, fmt.Errorf("download failed with status %d", resp.StatusCode)
+		if err != nil {
+			return resp.StatusCode, nil, err
+		}
+
+		if attempt < maxFloodRetries {
+			if delay, ok := floodDelay(resp, body); ok {
+				c.limiter.PauseUntil(time.Now().Add(delay))
+				continue
+			}
+		}
+		return resp.StatusCode, body, nil
+	}
+}
+
+// filePayload returns the payload identifying a file, with its password if it's protected.
+func filePayload(fileURL string, password string) map[string]string {
+	payload := map[string]string{"url": fileURL}
+	if password != "" {
+		payload["pass"] = password
+	}
+	return payload
+}
+
+// OneFichierFileID extract the file ID from a 1fichier.com URL (https://1fichier.com/?id&...)
+// The ID is case insensitive and returned lower-cased, so it can be used to compare links.
+func OneFichierFileID(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %s", rawURL)
+	}
+
+	fileID, _, _ := strings.Cut(parsedURL.RawQuery, "&")
+	if fileID == "" || strings.Contains(fileID, "=") {
+		return "", fmt.Errorf("1fichier id not found in URL: %s", rawURL)
 	}
 
-	return resp.Body, resp.ContentLength, resp.StatusCode, nil
+	return strings.ToLower(fileID), nil
+}
+
+// messageOf returns the error message of an API response, "" if there is none.
+func messageOf(message *string) string {
+	if message == nil {
+		return ""
+	}
+	return *message
 }
 
 func (c *oneFichierClient) setHeaders(req *http.Request) {
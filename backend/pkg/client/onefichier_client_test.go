@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"dlbackend/internal/config"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestConfig sets the configuration read by the clients: no API rate limit.
+func setupTestConfig(t *testing.T) {
+	t.Helper()
+	config.Cfg = &config.Config{}
+}
+
+// newTestAPI starts a fake 1fichier API answering every call with the status and body.
+// It returns a client with an API key of its own, so that the limiter is not shared.
+func newTestAPI(t *testing.T, handler http.HandlerFunc) (*oneFichierClient, *httptest.Server) {
+	t.Helper()
+	setupTestConfig(t)
+
+	// Each test starts with an empty file info cache
+	previous := fileInfos
+	fileInfos = newFileInfoCache(fileInfoCacheTTL, fileInfoCacheSize)
+	t.Cleanup(func() { fileInfos = previous })
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewOneFichierClient(server.URL, t.Name()).(*oneFichierClient), server
+}
+
+// answer returns a handler answering with the status code and the JSON body.
+func answer(statusCode int, body any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		message    string
+		want       error
+	}{
+		{name: "flood", statusCode: http.StatusForbidden, message: "Flood detected: IP Locked #38", want: ErrRateLimited},
+		{name: "too many requests", statusCode: http.StatusForbidden, message: "Too many requests, retry later", want: ErrRateLimited},
+		{name: "not authenticated", statusCode: http.StatusForbidden, message: "Not authenticated #247", want: ErrUnauthorized},
+		{name: "bad API key", statusCode: http.StatusForbidden, message: "Bad API key", want: ErrUnauthorized},
+		{name: "resource not found", statusCode: http.StatusForbidden, message: "Resource not found #469", want: ErrFileNotFound},
+		{name: "file deleted", statusCode: http.StatusOK, message: "File has been deleted", want: ErrFileNotFound},
+		{name: "password required", statusCode: http.StatusForbidden, message: "Password required", want: ErrPasswordRequired},
+		{name: "invalid password", statusCode: http.StatusForbidden, message: "Invalid password", want: ErrInvalidPassword},
+		{name: "wrong password", statusCode: http.StatusForbidden, message: "Wrong password", want: ErrInvalidPassword},
+		{name: "message wins over status", statusCode: http.StatusUnauthorized, message: "Resource not found #469", want: ErrFileNotFound},
+		{name: "401 without message", statusCode: http.StatusUnauthorized, want: ErrUnauthorized},
+		{name: "404 without message", statusCode: http.StatusNotFound, want: ErrFileNotFound},
+		{name: "410 without message", statusCode: http.StatusGone, want: ErrFileNotFound},
+		{name: "429 without message", statusCode: http.StatusTooManyRequests, want: ErrRateLimited},
+		{name: "unknown error", statusCode: http.StatusInternalServerError, message: "Internal error", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyAPIError(tt.statusCode, tt.message))
+
+			err := NewAPIError(tt.statusCode, tt.message)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			} else {
+				assert.Nil(t, errors.Unwrap(err))
+			}
+		})
+	}
+}
+
+func TestDownloadStatusError(t *testing.T) {
+	tests := []struct {
+		statusCode  int
+		expired     bool
+		rateLimited bool
+	}{
+		{statusCode: http.StatusForbidden, expired: true},
+		{statusCode: http.StatusGone, expired: true},
+		{statusCode: http.StatusTooManyRequests, rateLimited: true},
+		{statusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			err := error(&DownloadStatusError{StatusCode: tt.statusCode})
+			assert.Equal(t, tt.expired, errors.Is(err, ErrLinkExpired))
+			assert.Equal(t, tt.rateLimited, errors.Is(err, ErrRateLimited))
+		})
+	}
+}
+
+func TestOneFichierClient_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       any
+		want       error
+	}{
+		{
+			name:       "API key rejected",
+			statusCode: http.StatusUnauthorized,
+			body:       map[string]string{"status": "KO", "message": "Not authenticated #247"},
+			want:       ErrUnauthorized,
+		},
+		{
+			name:       "file not found",
+			statusCode: http.StatusForbidden,
+			body:       map[string]string{"status": "KO", "message": "Resource not found #469"},
+			want:       ErrFileNotFound,
+		},
+		{
+			name:       "password required",
+			statusCode: http.StatusForbidden,
+			body:       map[string]string{"status": "KO", "message": "Password required"},
+			want:       ErrPasswordRequired,
+		},
+		{
+			name:       "invalid password",
+			statusCode: http.StatusForbidden,
+			body:       map[string]string{"status": "KO", "message": "Invalid password"},
+			want:       ErrInvalidPassword,
+		},
+		{
+			name:       "not JSON",
+			statusCode: http.StatusNotFound,
+			body:       "not found",
+			want:       ErrFileNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestAPI(t, answer(tt.statusCode, tt.body))
+
+			_, err := client.GetFileInfo(context.Background(), "https://1fichier.com/?"+t.Name(), "")
+			assert.ErrorIs(t, err, tt.want)
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.statusCode, apiErr.StatusCode)
+
+			_, err = client.GetDownloadToken(context.Background(), "https://1fichier.com/?"+t.Name(), "")
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		client, _ := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer "+t.Name(), r.Header.Get("Authorization"))
+			var payload map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "secret", payload["pass"])
+
+			switch r.URL.Path {
+			case "/file/info.cgi":
+				answer(http.StatusOK, map[string]any{"url": payload["url"], "filename": "movie.mkv", "size": 42})(w, r)
+			case "/download/get_token.cgi":
+				answer(http.StatusOK, map[string]any{"url": "https://a-1.1fichier.com/token", "status": "OK"})(w, r)
+			}
+		})
+
+		info, err := client.GetFileInfo(context.Background(), "https://1fichier.com/?typed-success", "secret")
+		require.NoError(t, err)
+		assert.Equal(t, "movie.mkv", info.Filename)
+		assert.Equal(t, int64(42), info.Size)
+
+		token, err := client.GetDownloadToken(context.Background(), "https://1fichier.com/?typed-success", "secret")
+		require.NoError(t, err)
+		assert.Equal(t, "https://a-1.1fichier.com/token", token.URL)
+	})
+}
+
+func TestDynamicOneFichierClient(t *testing.T) {
+	var keys []string
+	_, server := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Authorization"))
+		answer(http.StatusOK, map[string]any{"url": "https://a-1.1fichier.com/token", "status": "OK"})(w, r)
+	})
+
+	apiKey := "first-key"
+	var keyErr error
+	client := NewDynamicOneFichierClient(server.URL, func() (string, error) { return apiKey, keyErr }).(*dynamicOneFichierClient)
+
+	_, err := client.GetDownloadToken(context.Background(), "https://1fichier.com/?dynamic", "")
+	require.NoError(t, err)
+	first := client.client
+	_, err = client.GetDownloadToken(context.Background(), "https://1fichier.com/?dynamic", "")
+	require.NoError(t, err)
+	assert.Same(t, first, client.client, "the client is kept while the key doesn't change")
+
+	apiKey = "second-key"
+	_, err = client.GetDownloadToken(context.Background(), "https://1fichier.com/?dynamic", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer first-key", "Bearer first-key", "Bearer second-key"}, keys)
+
+	apiKey = ""
+	_, err = client.GetDownloadToken(context.Background(), "https://1fichier.com/?dynamic", "")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	keyErr = errors.New("db error")
+	_, err = client.GetFileInfo(context.Background(), "https://1fichier.com/?dynamic", "")
+	assert.ErrorIs(t, err, keyErr)
+	assert.Len(t, keys, 3)
+}
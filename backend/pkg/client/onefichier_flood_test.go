@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFloodDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		retryAfter string
+		body       string
+		want       time.Duration
+		flood      bool
+	}{
+		{
+			name:       "Retry-After seconds",
+			statusCode: http.StatusForbidden,
+			retryAfter: "30",
+			body:       `{"status":"KO","message":"Flood detected: IP Locked #38"}`,
+			want:       30 * time.Second,
+			flood:      true,
+		},
+		{
+			name:       "Retry-After wins over the message",
+			statusCode: http.StatusTooManyRequests,
+			retryAfter: "5",
+			body:       `{"status":"KO","message":"Flood detected, retry in 10 minutes"}`,
+			want:       5 * time.Second,
+			flood:      true,
+		},
+		{
+			name:       "seconds in the message",
+			statusCode: http.StatusForbidden,
+			body:       `{"status":"KO","message":"Flood detected, retry in 45 seconds"}`,
+			want:       45 * time.Second,
+			flood:      true,
+		},
+		{
+			name:       "minutes in the message",
+			statusCode: http.StatusForbidden,
+			body:       `{"status":"KO","message":"Too many requests, wait 5 min"}`,
+			want:       5 * time.Minute,
+			flood:      true,
+		},
+		{
+			name:       "hours in the message",
+			statusCode: http.StatusForbidden,
+			body:       `{"status":"KO","message":"IP locked for 1 hour"}`,
+			want:       time.Hour,
+			flood:      true,
+		},
+		{
+			name:       "invalid Retry-After",
+			statusCode: http.StatusTooManyRequests,
+			retryAfter: "soon",
+			body:       `{"status":"KO","message":"Flood detected: IP Locked #38"}`,
+			want:       defaultFloodDelay,
+			flood:      true,
+		},
+		{
+			name:       "no wait hint",
+			statusCode: http.StatusForbidden,
+			body:       `{"status":"KO","message":"Flood detected: IP Locked #38"}`,
+			want:       defaultFloodDelay,
+			flood:      true,
+		},
+		{
+			name:       "429 without body",
+			statusCode: http.StatusTooManyRequests,
+			want:       defaultFloodDelay,
+			flood:      true,
+		},
+		{
+			name:       "not a flood",
+			statusCode: http.StatusForbidden,
+			retryAfter: "30",
+			body:       `{"status":"KO","message":"Resource not found #469"}`,
+		},
+		{
+			name:       "success",
+			statusCode: http.StatusOK,
+			body:       `{"url":"https://a-1.1fichier.com/token","status":"OK"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.statusCode, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			delay, ok := floodDelay(resp, []byte(tt.body))
+			assert.Equal(t, tt.flood, ok)
+			assert.Equal(t, tt.want, delay)
+		})
+	}
+
+	t.Run("Retry-After date", func(t *testing.T) {
+		resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+		resp.Header.Set("Retry-After", time.Now().Add(2*time.Minute).UTC().Format(http.TimeFormat))
+
+		delay, ok := floodDelay(resp, nil)
+		assert.True(t, ok)
+		assert.InDelta(t, float64(2*time.Minute), float64(delay), float64(2*time.Second))
+	})
+}
+
+// floodAPI is a fake 1fichier API answering the first floods calls with a flood
+// error asking to wait a second, then with the token.
+type floodAPI struct {
+	mu     sync.Mutex
+	floods int
+	calls  []time.Time
+}
+
+func (a *floodAPI) handle(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.calls = append(a.calls, time.Now())
+	flood := len(a.calls) <= a.floods
+	a.mu.Unlock()
+
+	if flood {
+		w.Header().Set("Retry-After", "1")
+		answer(http.StatusForbidden, map[string]string{"status": "KO", "message": "Flood detected: IP Locked #38"})(w, r)
+		return
+	}
+	answer(http.StatusOK, map[string]string{"url": "https://a-1.1fichier.com/token", "status": "OK"})(w, r)
+}
+
+func TestOneFichierClient_Flood(t *testing.T) {
+	t.Run("pauses the account and retries", func(t *testing.T) {
+		api := &floodAPI{floods: 1}
+		client, server := newTestAPI(t, api.handle)
+
+		token, err := client.GetDownloadToken(context.Background(), "https://1fichier.com/?flood-retry", "")
+		require.NoError(t, err)
+		assert.Equal(t, "https://a-1.1fichier.com/token", token.URL)
+
+		require.Len(t, api.calls, 2)
+		assert.GreaterOrEqual(t, api.calls[1].Sub(api.calls[0]), 900*time.Millisecond)
+
+		// The pause is shared by the clients of the same account
+		other := NewOneFichierClient(server.URL, t.Name()).(*oneFichierClient)
+		assert.Same(t, client.limiter, other.limiter)
+	})
+
+	t.Run("pause holds the other calls of the account", func(t *testing.T) {
+		api := &floodAPI{}
+		client, server := newTestAPI(t, api.handle)
+		other := NewOneFichierClient(server.URL, t.Name())
+
+		_, err := client.GetDownloadToken(context.Background(), "https://1fichier.com/?flood-first", "")
+		require.NoError(t, err)
+
+		start := time.Now()
+		client.limiter.PauseUntil(start.Add(time.Second))
+		_, err = other.GetDownloadToken(context.Background(), "https://1fichier.com/?flood-second", "")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+	})
+
+	t.Run("gives up after maxFloodRetries", func(t *testing.T) {
+		api := &floodAPI{floods: maxFloodRetries + 1}
+		client, _ := newTestAPI(t, api.handle)
+
+		_, err := client.GetDownloadToken(context.Background(), "https://1fichier.com/?flood-give-up", "")
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.Len(t, api.calls, maxFloodRetries+1)
+	})
+
+	t.Run("canceled during the pause", func(t *testing.T) {
+		api := &floodAPI{floods: maxFloodRetries + 1}
+		client, _ := newTestAPI(t, api.handle)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err := client.GetFileInfo(ctx, "https://1fichier.com/?flood-canceled", "")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Len(t, api.calls, 1)
+	})
+}
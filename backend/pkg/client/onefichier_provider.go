@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// oneFichierTokenTTL is the validity of a 1fichier download URL.
+const oneFichierTokenTTL = 5 * time.Minute
+
+// oneFichierProvider is the Provider of the 1fichier.com links, on top of the API client.
+type oneFichierProvider struct {
+	client OneFichierClient
+}
+
+// NewOneFichierProvider returns the 1fichier provider using the API client.
+func NewOneFichierProvider(client OneFichierClient) Provider {
+	return &oneFichierProvider{client: client}
+}
+
+func (p *oneFichierProvider) Name() string {
+	return ProviderOneFichier
+}
+
+// Match reports whether the link is on 1fichier.com.
+func (p *oneFichierProvider) Match(fileURL string) bool {
+	parsedURL, err := url.Parse(strings.TrimSpace(fileURL))
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsedURL.Host)
+	return host == "1fichier.com" || host == "www.1fichier.com"
+}
+
+func (p *oneFichierProvider) GetFileInfo(ctx context.Context, fileURL string, password string) (*FileInfo, error) {
+	info, err := p.client.GetFileInfo(ctx, fileURL, password)
+	if err != nil {
+		return nil, err
+	}
+	return &FileInfo{
+		Filename:    info.Filename,
+		Size:        info.Size,
+		Checksum:    info.Checksum,
+		ContentType: info.ContentType,
+	}, nil
+}
+
+// ResolveDownloadURL requests a download token, valid for 5 minutes.
+func (p *oneFichierProvider) ResolveDownloadURL(ctx context.Context, fileURL string, password string) (*DownloadLink, error) {
+	token, err := p.client.GetDownloadToken(ctx, fileURL, password)
+	if err != nil {
+		return nil, err
+	}
+	return &DownloadLink{URL: token.URL, TTL: oneFichierTokenTTL}, nil
+}
+
+func (p *oneFichierProvider) DownloadFile(ctx context.Context, downloadURL string, offset int64) (io.ReadCloser, int64, int, error) {
+	return p.client.DownloadFile(ctx, downloadURL, offset)
+}
+
+func (p *oneFichierProvider) DownloadRange(ctx context.Context, downloadURL string, start int64, end int64) (io.ReadCloser, int64, int, error) {
+	return p.client.DownloadRange(ctx, downloadURL, start, end)
+}
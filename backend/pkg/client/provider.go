@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ===============================
+// Provider Interface
+// ===============================
+
+// Provider resolves and streams the files of a hosting service. The download
+// pipeline only depends on this interface: the provider of a link is selected
+// with Providers.ForURL, and its name is recorded on the download.
+type Provider interface {
+	// Name identifies the provider, e.g. ProviderOneFichier
+	Name() string
+	// Match reports whether the provider handles the link
+	Match(fileURL string) bool
+	// GetFileInfo returns the metadata of the file behind the link
+	GetFileInfo(ctx context.Context, fileURL string, password string) (*FileInfo, error)
+	// ResolveDownloadURL returns the URL streaming the file
+	ResolveDownloadURL(ctx context.Context, fileURL string, password string) (*DownloadLink, error)
+	// DownloadFile streams the file from the offset
+	DownloadFile(ctx context.Context, downloadURL string, offset int64) (io.ReadCloser, int64, int, error)
+	// DownloadRange streams a byte range of the file (end is inclusive)
+	DownloadRange(ctx context.Context, downloadURL string, start int64, end int64) (io.ReadCloser, int64, int, error)
+}
+
+// Names of the providers, recorded on the downloads.
+const (
+	ProviderOneFichier = "1fichier"
+	ProviderDirect     = "direct"
+)
+
+// FileInfo is the metadata of a file, as announced by its provider.
+type FileInfo struct {
+	Filename    string
+	Size        int64  // 0 if unknown
+	Checksum    string // "" if unknown
+	ContentType string // "" if unknown
+}
+
+// DownloadLink is the URL streaming a file.
+type DownloadLink struct {
+	URL string
+	TTL time.Duration // Validity of the URL, 0 if it doesn't expire
+}
+
+// ErrUnsupportedURL is returned when no provider handles a link.
+var ErrUnsupportedURL = errors.New("unsupported URL")
+
+// ===============================
+// Provider Selection
+// ===============================
+
+// Providers is the list of the available providers, in matching order.
+type Providers []Provider
+
+// NewProviders returns the available providers, built once and shared by the services
+// and the workers. The direct link provider matches any public HTTP(S) link, so it comes
+// last; allowPrivate also lets it reach private network addresses.
+func NewProviders(oneFichierClient OneFichierClient, allowPrivate bool) Providers {
+	return Providers{
+		NewOneFichierProvider(oneFichierClient),
+		NewDirectProvider(allowPrivate),
+	}
+}
+
+// ForURL returns the first provider handling the link.
+func (p Providers) ForURL(fileURL string) (Provider, error) {
+	for _, provider := range p {
+		if provider.Match(fileURL) {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedURL, fileURL)
+}
+
+// ByName returns the provider recorded on a download. An empty name is the
+// provider of the downloads created before providers were recorded: 1fichier.
+func (p Providers) ByName(name string) (Provider, error) {
+	if name == "" {
+		name = ProviderOneFichier
+	}
+	for _, provider := range p {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown provider: %s", name)
+}
+
+// ===============================
+// Streaming
+// ===============================
+
+// openStream sends the GET request of a file download, with the Range header if any,
+// and returns the body, its length and the status code. A status other than 200 or
+// 206 is returned as a DownloadStatusError.
+func openStream(ctx context.Context, httpClient *http.Client, downloadURL string, rangeHeader string) (io.ReadCloser, int64, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, 0, 0, &DownloadStatusError{StatusCode: resp.StatusCode}
+	}
+
+	return resp.Body, resp.ContentLength, resp.StatusCode, nil
+}
+
+// offsetRange returns the Range header of a download from the offset, "" from the start.
+func offsetRange(offset int64) string {
+	if offset > 0 {
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket limiting a byte rate. The bucket holds up to one
+// second of tokens, so short bursts are smoothed out without exceeding the rate.
+// The rate can be changed at any time, waiting callers pick it up immediately.
+// A nil Limiter, or a rate of 0, means unlimited.
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64 // Bytes per second, 0 = unlimited
+	tokens  float64
+	last    time.Time
+	changed chan struct{} // Closed and replaced on every rate change to wake up waiters
+}
+
+// NewLimiter creates a limiter allowing bytesPerSec bytes per second (0 = unlimited).
+func NewLimiter(bytesPerSec int64) *Limiter {
+	l := &Limiter{changed: make(chan struct{})}
+	l.SetRate(bytesPerSec)
+	return l
+}
+
+// SetRate changes the limit (0 = unlimited).
+func (l *Limiter) SetRate(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if float64(max(bytesPerSec, 0)) == l.rate {
+		return
+	}
+
+	l.rate = float64(max(bytesPerSec, 0))
+	l.tokens = min(l.tokens, l.rate)
+	l.last = time.Now()
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Rate returns the current limit in bytes per second (0 = unlimited).
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int64(l.rate)
+}
+
+// WaitN blocks until n bytes can be consumed, or until the context is done.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	remaining := float64(n)
+	for remaining > 0 {
+		l.mu.Lock()
+		if l.rate == 0 {
+			l.mu.Unlock()
+			return nil
+		}
+
+		l.refill()
+
+		// Consume at most a full bucket at once so large reads can't wait forever
+		need := min(remaining, l.rate)
+		if l.tokens >= need {
+			l.tokens -= need
+			remaining -= need
+			l.mu.Unlock()
+			continue
+		}
+
+		delay := time.Duration((need - l.tokens) / l.rate * float64(time.Second))
+		changed := l.changed
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+
+	return nil
+}
+
+// refill adds the tokens earned since the last refill. Must be called with mu held.
+func (l *Limiter) refill() {
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.rate)
+	l.last = now
+}
+
+// minReadSize is the smallest read allowed by a Reader, whatever the rate.
+const minReadSize = 1024
+
+// Reader wraps an io.Reader and waits on each limiter after every read.
+// Reads are shortened to about 100ms worth of data at the lowest rate, so the
+// caller gets control back regularly even on a very slow limit.
+// Nil limiters are ignored.
+type Reader struct {
+	ctx      context.Context
+	reader   io.Reader
+	limiters []*Limiter
+}
+
+// NewReader returns a reader limited by all the given limiters.
+func NewReader(ctx context.Context, reader io.Reader, limiters ...*Limiter) *Reader {
+	return &Reader{ctx: ctx, reader: reader, limiters: limiters}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for _, limiter := range r.limiters {
+		if rate := limiter.Rate(); rate > 0 {
+			p = p[:min(len(p), max(int(rate/10), minReadSize))]
+		}
+	}
+
+	n, err := r.reader.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+
+	for _, limiter := range r.limiters {
+		if waitErr := limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Unlimited(t *testing.T) {
+	var nilLimiter *Limiter
+	assert.NoError(t, nilLimiter.WaitN(context.Background(), 1<<30))
+
+	limiter := NewLimiter(0)
+	start := time.Now()
+	assert.NoError(t, limiter.WaitN(context.Background(), 1<<30))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestLimiter_WaitN(t *testing.T) {
+	limiter := NewLimiter(10_000)
+
+	start := time.Now()
+	require.NoError(t, limiter.WaitN(context.Background(), 3_000))
+	elapsed := time.Since(start)
+
+	assert.GreaterOrEqual(t, elapsed, 250*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
+
+func TestLimiter_SetRateWakesWaiters(t *testing.T) {
+	limiter := NewLimiter(1)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- limiter.WaitN(context.Background(), 1_000)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	limiter.SetRate(0)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("waiter not released by the rate change")
+	}
+}
+
+func TestLimiter_ContextCancelled(t *testing.T) {
+	limiter := NewLimiter(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := limiter.WaitN(ctx, 1_000)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 4_000)
+	reader := NewReader(context.Background(), bytes.NewReader(data), nil, NewLimiter(20_000))
+
+	start := time.Now()
+	read, err := io.ReadAll(reader)
+	elapsed := time.Since(start)
+
+	require.NoError(t, err)
+	assert.Equal(t, data, read)
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RequestLimiter limits a request rate. Requests are spaced out evenly over the
+// period, and callers are queued in arrival order rather than rejected. It can be
+// paused, e.g. when the server asks to wait, and callers queued for a slot within
+// the pause wait for its end. A nil RequestLimiter means unlimited.
+type RequestLimiter struct {
+	mu          sync.Mutex
+	interval    time.Duration // Time between two requests, 0 = unlimited
+	next        time.Time     // Earliest slot of the next request
+	pausedUntil time.Time
+}
+
+// NewRequestLimiter creates a limiter allowing requests per period (0 = unlimited).
+func NewRequestLimiter(requests int, period time.Duration) *RequestLimiter {
+	l := &RequestLimiter{}
+	if requests > 0 {
+		l.interval = period / time.Duration(requests)
+	}
+	return l
+}
+
+// Wait blocks until the caller can send its request, or until the context is done.
+func (l *RequestLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		// Reserve the next slot, so that later callers queue after this one
+		l.mu.Lock()
+		now := time.Now()
+		slot := now
+		if l.next.After(slot) {
+			slot = l.next
+		}
+		if l.pausedUntil.After(slot) {
+			slot = l.pausedUntil
+		}
+		l.next = slot.Add(l.interval)
+		l.mu.Unlock()
+
+		if delay := time.Until(slot); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		// A pause received while waiting pushes the request back
+		l.mu.Lock()
+		paused := l.pausedUntil.After(time.Now())
+		l.mu.Unlock()
+		if !paused {
+			return nil
+		}
+	}
+}
+
+// PauseUntil holds all the requests until the given time. A pause ending later is kept.
+func (l *RequestLimiter) PauseUntil(until time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimiter_Unlimited(t *testing.T) {
+	var nilLimiter *RequestLimiter
+	assert.NoError(t, nilLimiter.Wait(context.Background()))
+	nilLimiter.PauseUntil(time.Now().Add(time.Hour))
+
+	limiter := NewRequestLimiter(0, time.Minute)
+	start := time.Now()
+	for range 100 {
+		require.NoError(t, limiter.Wait(context.Background()))
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRequestLimiter_Wait(t *testing.T) {
+	limiter := NewRequestLimiter(10, time.Second)
+
+	start := time.Now()
+	for range 4 {
+		require.NoError(t, limiter.Wait(context.Background()))
+	}
+	elapsed := time.Since(start)
+
+	// The first request is immediate, the next ones are 100ms apart
+	assert.GreaterOrEqual(t, elapsed, 300*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
+
+func TestRequestLimiter_PauseUntil(t *testing.T) {
+	limiter := NewRequestLimiter(100, time.Second)
+
+	done := make(chan time.Time, 1)
+	require.NoError(t, limiter.Wait(context.Background()))
+	go func() {
+		// Queued for a slot within the pause
+		time.Sleep(time.Millisecond)
+		limiter.Wait(context.Background())
+		done <- time.Now()
+	}()
+
+	start := time.Now()
+	limiter.PauseUntil(start.Add(200 * time.Millisecond))
+	limiter.PauseUntil(start.Add(50 * time.Millisecond))
+
+	select {
+	case at := <-done:
+		assert.GreaterOrEqual(t, at.Sub(start), 200*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("waiter not released after the pause")
+	}
+}
+
+func TestRequestLimiter_ContextCancelled(t *testing.T) {
+	limiter := NewRequestLimiter(1, time.Hour)
+	require.NoError(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := limiter.Wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
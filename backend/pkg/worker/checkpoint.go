@@ -0,0 +1,278 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// CHECKPOINTS - Stream integrity sidecar for crash-safe resume
+// ============================================================================
+
+// checkpointInterval is the time between two checkpoints of a running download.
+const checkpointInterval = 5 * time.Second
+
+var integrityTable = crc64.MakeTable(crc64.ECMA)
+
+// checkpoint is the content of the sidecar file written next to the temp file of a
+// single connection download: the first Offset bytes of the temp file were synced
+// to the disk, and their CRC-64 is Hash. It survives a crash between a write and
+// the database update, so that a resume only loses the unconfirmed bytes.
+// A multi-connection download records each of its byte ranges in Segments instead.
+type checkpoint struct {
+	DownloadID string              `json:"downloadId"`
+	Offset     int64               `json:"offset"`
+	Hash       string              `json:"hash"`
+	Segments   []segmentCheckpoint `json:"segments,omitempty"`
+}
+
+// segmentCheckpoint records a byte range: its first Downloaded bytes from Start were
+// synced to the disk, and their CRC-64 is Hash.
+type segmentCheckpoint struct {
+	Start      int64  `json:"start"`
+	Downloaded int64  `json:"downloaded"`
+	Hash       string `json:"hash"`
+}
+
+// newIntegrityHash returns the rolling hash saved in checkpoints.
+func newIntegrityHash() hash.Hash {
+	return crc64.New(integrityTable)
+}
+
+// readCheckpoint reads the checkpoint of the download, nil if there is none or if it can't be read.
+func readCheckpoint(download *model.Download) *checkpoint {
+	path, err := download.CheckpointFilePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Failed to read checkpoint of download %s: %v", download.ID, err)
+		}
+		return nil
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil || cp.DownloadID != download.ID || cp.Offset < 0 {
+		log.Warnf("Ignoring invalid checkpoint of download %s", download.ID)
+		return nil
+	}
+	return &cp
+}
+
+// writeCheckpoint replaces the checkpoint of the download atomically: the new
+// content is synced to a separate file, then renamed over the previous one.
+func writeCheckpoint(download *model.Download, cp checkpoint) error {
+	path, err := download.CheckpointFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path + ".new")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".new", path)
+}
+
+// removeCheckpoint deletes the checkpoint of the download, if any.
+func removeCheckpoint(download *model.Download) {
+	if path, err := download.CheckpointFilePath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// loadCheckpoint moves a single connection download to the offset of its checkpoint,
+// if any: the bytes confirmed on disk, whatever was saved in the database. The data
+// is only trusted once verifyCheckpoint matched its hash. A download starting from
+// zero ignores it.
+func (w *DownloadWorker) loadCheckpoint() *checkpoint {
+	if w.download.DownloadedBytes == 0 {
+		return nil
+	}
+	cp := readCheckpoint(w.download)
+	if cp == nil || cp.Offset == w.download.DownloadedBytes {
+		return cp
+	}
+
+	log.Infof("Download %s resumes from its checkpoint: offset %d instead of %d", w.download.ID, cp.Offset, w.download.DownloadedBytes)
+	w.UpdateDownload(func(d *model.Download) {
+		d.DownloadedBytes = cp.Offset
+		if d.FileSize != nil && *d.FileSize > 0 {
+			d.Progress = float64(d.DownloadedBytes) / float64(*d.FileSize) * 100
+		}
+	})
+	return cp
+}
+
+// verifyCheckpoint compares the integrity hash of the bytes on disk, as initialized
+// by initHasher, with the checkpoint. On match, the unconfirmed bytes past the offset
+// are dropped; on mismatch, the download restarts from zero.
+func (w *DownloadWorker) verifyCheckpoint(tempPath string, cp *checkpoint) error {
+	if cp == nil || cp.Offset != w.download.DownloadedBytes || w.download.DownloadedBytes == 0 {
+		return nil
+	}
+
+	if hex.EncodeToString(w.integrity.Sum(nil)) != cp.Hash {
+		log.Warnf("Temp file of %s does not match its checkpoint, restarting from zero", w.download.ID)
+		w.UpdateDownload(func(d *model.Download) {
+			d.DownloadedBytes = 0
+			d.Progress = 0
+		})
+		w.integrity.Reset()
+		if w.hasher != nil {
+			w.hasher.Reset()
+		}
+		return nil
+	}
+
+	if err := os.Truncate(tempPath, cp.Offset); err != nil {
+		return fmt.Errorf("failed to truncate temp file: %w", err)
+	}
+	return nil
+}
+
+// saveCheckpoint syncs the temp file and records the current offset in the checkpoint.
+// A failure is only logged: the download goes on, a resume just loses more data.
+func (w *DownloadWorker) saveCheckpoint() {
+	if w.integrity == nil || w.file == nil {
+		return
+	}
+
+	if err := w.file.Sync(); err != nil {
+		log.Warnf("Failed to sync temp file of %s: %v", w.download.ID, err)
+		return
+	}
+	cp := checkpoint{
+		DownloadID: w.download.ID,
+		Offset:     w.download.DownloadedBytes,
+		Hash:       hex.EncodeToString(w.integrity.Sum(nil)),
+	}
+	if err := writeCheckpoint(w.download, cp); err != nil {
+		log.Warnf("Failed to write checkpoint of %s: %v", w.download.ID, err)
+	}
+}
+
+// verifySegments moves each byte range of a multi-connection download to the offset of
+// its checkpoint, once the hash of its bytes on disk matched; a mismatching range restarts
+// from zero. Without a checkpoint of the same ranges, all of them restart from zero: the
+// holes of a preallocated file can't be told apart from downloaded bytes.
+func (w *DownloadWorker) verifySegments(tempPath string) error {
+	w.segmentHashes = make([]hash.Hash, len(w.download.Segments))
+	for i := range w.segmentHashes {
+		w.segmentHashes[i] = newIntegrityHash()
+	}
+	if w.download.DownloadedBytes == 0 {
+		return nil
+	}
+
+	downloaded := make([]int64, len(w.download.Segments))
+	cp := readCheckpoint(w.download)
+	if cp == nil || !sameSegments(cp.Segments, w.download.Segments) {
+		log.Warnf("No checkpoint for the segments of %s, restarting from zero", w.download.ID)
+	} else {
+		file, err := os.Open(tempPath)
+		if err != nil {
+			return fmt.Errorf("failed to open temp file: %w", err)
+		}
+		defer file.Close()
+
+		for i, segment := range w.download.Segments {
+			saved := min(max(cp.Segments[i].Downloaded, 0), segment.End-segment.Start+1)
+			if saved == 0 {
+				continue
+			}
+			integrity := w.segmentHashes[i]
+			if _, err := io.Copy(integrity, io.NewSectionReader(file, segment.Start, saved)); err != nil {
+				return fmt.Errorf("failed to read temp file: %w", err)
+			}
+			if hex.EncodeToString(integrity.Sum(nil)) != cp.Segments[i].Hash {
+				log.Warnf("Segment %d of %s does not match its checkpoint, restarting it from zero", i, w.download.ID)
+				integrity.Reset()
+				continue
+			}
+			downloaded[i] = saved
+		}
+	}
+
+	w.UpdateDownload(func(d *model.Download) {
+		d.DownloadedBytes = 0
+		for i := range d.Segments {
+			d.Segments[i].Downloaded = downloaded[i]
+			d.DownloadedBytes += downloaded[i]
+		}
+		if d.FileSize != nil && *d.FileSize > 0 {
+			d.Progress = float64(d.DownloadedBytes) / float64(*d.FileSize) * 100
+		}
+	})
+	return nil
+}
+
+// sameSegments reports whether the checkpoint records the byte ranges of the download.
+func sameSegments(saved []segmentCheckpoint, segments []model.DownloadSegment) bool {
+	if len(saved) != len(segments) {
+		return false
+	}
+	for i := range saved {
+		if saved[i].Start != segments[i].Start {
+			return false
+		}
+	}
+	return true
+}
+
+// saveSegmentsCheckpoint syncs the temp file and records the offset of each byte range in
+// the checkpoint. The offsets are read before the sync, so they only cover synced bytes.
+// A failure is only logged, as for saveCheckpoint.
+func (w *DownloadWorker) saveSegmentsCheckpoint() {
+	if w.segmentHashes == nil || w.file == nil {
+		return
+	}
+
+	w.mu.Lock()
+	cp := checkpoint{
+		DownloadID: w.download.ID,
+		Segments:   make([]segmentCheckpoint, len(w.download.Segments)),
+	}
+	for i, segment := range w.download.Segments {
+		cp.Segments[i] = segmentCheckpoint{
+			Start:      segment.Start,
+			Downloaded: segment.Downloaded,
+			Hash:       hex.EncodeToString(w.segmentHashes[i].Sum(nil)),
+		}
+	}
+	w.mu.Unlock()
+
+	if err := w.file.Sync(); err != nil {
+		log.Warnf("Failed to sync temp file of %s: %v", w.download.ID, err)
+		return
+	}
+	if err := writeCheckpoint(w.download, cp); err != nil {
+		log.Warnf("Failed to write checkpoint of %s: %v", w.download.ID, err)
+	}
+}
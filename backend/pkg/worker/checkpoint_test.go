@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"encoding/hex"
+	"hash/crc64"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// integrityHex returns the checkpoint hash of the content.
+func integrityHex(content string) string {
+	h := crc64.New(integrityTable)
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestDownloadWorker_Checkpoint(t *testing.T) {
+	setupTestConfig(t)
+
+	const downloadURL = "https://download.1fichier.com/test"
+
+	t.Run("download records the offset reached", func(t *testing.T) {
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withContent(""))
+		worker.mockClient.On("DownloadFile", *worker.download.DownloadURL, int64(0)).Return(
+			&MockReadCloser{reader: strings.NewReader("hello world")}, int64(11), http.StatusOK, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		completed, err := worker.downloadChunk()
+		worker.closeFile()
+		require.NoError(t, err)
+		require.True(t, completed)
+
+		cp := readCheckpoint(worker.download)
+		require.NotNil(t, cp)
+		assert.Equal(t, int64(11), cp.Offset)
+		assert.Equal(t, integrityHex("hello world"), cp.Hash)
+	})
+
+	t.Run("resume drops the bytes past the checkpoint", func(t *testing.T) {
+		// The database is ahead of the data synced to the disk
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withDownloaded(8), withContent("hello wXXX"))
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "test-id", Offset: 6, Hash: integrityHex("hello ")}))
+
+		require.NoError(t, worker.prepareFile())
+		worker.closeFile()
+
+		assert.Equal(t, int64(6), worker.download.DownloadedBytes)
+		stat, err := os.Stat(worker.tempPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), stat.Size())
+	})
+
+	t.Run("resume goes past the database offset", func(t *testing.T) {
+		// Crash between a checkpoint and the database update
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withDownloaded(3), withContent("hello "))
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "test-id", Offset: 6, Hash: integrityHex("hello ")}))
+		require.True(t, tempFileMatches(worker.download))
+
+		require.NoError(t, worker.prepareFile())
+		worker.closeFile()
+
+		assert.Equal(t, int64(6), worker.download.DownloadedBytes)
+	})
+
+	t.Run("hash mismatch restarts from zero", func(t *testing.T) {
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withDownloaded(6), withContent("hellO "))
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "test-id", Offset: 6, Hash: integrityHex("hello ")}))
+
+		require.NoError(t, worker.prepareFile())
+		worker.closeFile()
+
+		assert.Zero(t, worker.download.DownloadedBytes)
+		assert.Nil(t, readCheckpoint(worker.download))
+		stat, err := os.Stat(worker.tempPath)
+		require.NoError(t, err)
+		assert.Zero(t, stat.Size())
+	})
+
+	t.Run("checkpoint of another download is ignored", func(t *testing.T) {
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withDownloaded(3), withContent("hello "))
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "other-id", Offset: 6, Hash: integrityHex("hello ")}))
+
+		require.NoError(t, worker.prepareFile())
+		worker.closeFile()
+
+		assert.Equal(t, int64(3), worker.download.DownloadedBytes)
+	})
+
+	t.Run("completion removes the checkpoint", func(t *testing.T) {
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withDownloaded(11), withContent("hello world"))
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "test-id", Offset: 11, Hash: integrityHex("hello world")}))
+
+		require.NoError(t, worker.complete())
+		finalPath, _ := worker.download.FinalFilePath()
+		defer os.Remove(finalPath)
+
+		assert.Equal(t, model.StatusCompleted, worker.download.Status)
+		assert.Nil(t, readCheckpoint(worker.download))
+	})
+
+	t.Run("delete removes the temp file and the checkpoint", func(t *testing.T) {
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withDownloaded(6), withContent("hello "))
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "test-id", Offset: 6, Hash: integrityHex("hello ")}))
+		checkpointPath, _ := worker.download.CheckpointFilePath()
+
+		require.NoError(t, RemoveTempFiles(worker.download))
+
+		assert.NoFileExists(t, worker.tempPath)
+		assert.NoFileExists(t, checkpointPath)
+	})
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jzelinskie/whirlpool"
+)
+
+// ============================================================================
+// CHECKSUM VERIFICATION
+// ============================================================================
+
+// newChecksumHash returns the hash algorithm matching the expected checksum,
+// or nil when the checksum format is unknown and can't be verified.
+// 1fichier checksums are Whirlpool digests (128 hex characters).
+func newChecksumHash(checksum string) hash.Hash {
+	if _, err := hex.DecodeString(checksum); err != nil {
+		return nil
+	}
+
+	switch len(checksum) {
+	case 128:
+		return whirlpool.New()
+	case 64:
+		return sha256.New()
+	case 40:
+		return sha1.New()
+	case 32:
+		return md5.New()
+	default:
+		return nil
+	}
+}
+
+// hashFilePrefix feeds the first n bytes of the file into the hash.
+func hashFilePrefix(h io.Writer, path string, n int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	written, err := io.Copy(h, io.LimitReader(file, n))
+	if err != nil {
+		return err
+	}
+	if written != n {
+		return fmt.Errorf("file is shorter than expected: %d < %d bytes", written, n)
+	}
+	return nil
+}
+
+// expectedChecksum returns the normalized checksum to verify, or "" if there is none.
+func (w *DownloadWorker) expectedChecksum() string {
+	if w.download.Checksum == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(*w.download.Checksum))
+}
+
+// initHasher prepares the incremental hashes of the temp file: the checksum and the
+// integrity hash of the checkpoints. When resuming, the bytes already on disk are
+// hashed once so that the digests cover the whole file.
+func (w *DownloadWorker) initHasher(tempPath string) error {
+	w.hasher = newChecksumHash(w.expectedChecksum())
+	w.integrity = newIntegrityHash()
+	if w.download.DownloadedBytes == 0 {
+		return nil
+	}
+
+	h := io.Writer(w.integrity)
+	if w.hasher != nil {
+		h = io.MultiWriter(w.hasher, w.integrity)
+	}
+	if err := hashFilePrefix(h, tempPath, w.download.DownloadedBytes); err != nil {
+		return fmt.Errorf("failed to hash existing temp file: %w", err)
+	}
+	return nil
+}
+
+// verifyChecksum compares the temp file digest with the checksum announced by 1fichier.
+// The incremental hash is used when available, otherwise the file is hashed in full.
+func (w *DownloadWorker) verifyChecksum(tempPath string) error {
+	expected := w.expectedChecksum()
+
+	h := w.hasher
+	if h == nil {
+		h = newChecksumHash(expected)
+		if h == nil {
+			return nil // Unknown checksum format, nothing to verify
+		}
+		stat, err := os.Stat(tempPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat temp file: %w", err)
+		}
+		if err := hashFilePrefix(h, tempPath, stat.Size()); err != nil {
+			return fmt.Errorf("failed to hash temp file: %w", err)
+		}
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
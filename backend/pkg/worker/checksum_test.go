@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"dlbackend/internal/model"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jzelinskie/whirlpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func whirlpoolHex(data string) string {
+	h := whirlpool.New()
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestNewChecksumHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		checksum string
+		wantNil  bool
+	}{
+		{name: "whirlpool", checksum: whirlpoolHex("data"), wantNil: false},
+		{name: "sha256", checksum: strings.Repeat("a", 64), wantNil: false},
+		{name: "sha1", checksum: strings.Repeat("a", 40), wantNil: false},
+		{name: "md5", checksum: strings.Repeat("a", 32), wantNil: false},
+		{name: "empty", checksum: "", wantNil: true},
+		{name: "unknown length", checksum: "abc123", wantNil: true},
+		{name: "not hex", checksum: strings.Repeat("z", 128), wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantNil, newChecksumHash(tt.checksum) == nil)
+		})
+	}
+}
+
+func TestDownloadWorker_ChecksumVerification(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("matching checksum completes", func(t *testing.T) {
+		worker := newTestWorker(t, withChecksum(whirlpoolHex("test content")), withContent("test content"))
+		tempPath := worker.tempPath
+
+		err := worker.complete()
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusCompleted, worker.download.Status)
+
+		finalPath, _ := worker.download.FinalFilePath()
+		_, err = os.Stat(finalPath)
+		assert.NoError(t, err)
+		_, err = os.Stat(tempPath)
+		assert.True(t, os.IsNotExist(err))
+		os.Remove(finalPath)
+	})
+
+	t.Run("mismatch marks the download as corrupted", func(t *testing.T) {
+		worker := newTestWorker(t, withChecksum(whirlpoolHex("expected content")), withContent("corrupted content"))
+		tempPath := worker.tempPath
+
+		err := worker.complete()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch")
+		assert.Equal(t, model.StatusCorrupted, worker.download.Status)
+		assert.NotNil(t, worker.download.ErrorMessage)
+
+		// The temp file is kept and nothing is finalized
+		_, err = os.Stat(tempPath)
+		assert.NoError(t, err)
+		finalPath, _ := worker.download.FinalFilePath()
+		_, err = os.Stat(finalPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("incremental hash covers resumed data", func(t *testing.T) {
+		sum := sha256.Sum256([]byte("hello world"))
+		downloadURL := "https://download.1fichier.com/test"
+		worker := newTestWorker(t,
+			withChecksum(hex.EncodeToString(sum[:])),
+			withContent("hello "),
+			withDownloaded(6),
+			withDownloadURL(downloadURL),
+		)
+
+		worker.mockClient.On("DownloadFile", downloadURL, int64(6)).Return(
+			&MockReadCloser{reader: strings.NewReader("world")}, int64(5), http.StatusPartialContent, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		completed, err := worker.downloadChunk()
+		worker.closeFile()
+		require.NoError(t, err)
+		require.True(t, completed)
+		require.NotNil(t, worker.hasher)
+
+		assert.NoError(t, worker.verifyChecksum(worker.tempPath))
+	})
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"dlbackend/internal/utils"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// CONFLICTS - Duplicates found once the file info is known
+// ============================================================================
+
+// errConflictSkipped stops a duplicated download with the SKIP policy: it is
+// completed without downloading anything.
+var errConflictSkipped = errors.New("duplicated download skipped")
+
+// checkConflicts applies the conflict policy to the duplicates found since the download
+// was created, or missed because the file info couldn't be fetched then: a completed
+// download of the same file (checksum), or an unfinished one with the same final path.
+// A final file already on disk is handled on completion.
+func (w *DownloadWorker) checkConflicts() error {
+	policy := w.download.ResolveConflictPolicy(w.conflictPolicy)
+
+	if w.sameFile != nil {
+		same, err := w.sameFile(w.download)
+		if err != nil {
+			return err
+		}
+		if same != nil {
+			log.Warnf("Download %s: same file as download %s, applying policy %s", w.download.ID, same.ID, policy)
+			switch policy {
+			case model.ConflictRename, model.ConflictOverwrite:
+				// Downloaded again, the final path decides
+			case model.ConflictSkip:
+				return errConflictSkipped
+			default:
+				w.UpdateDownload(func(d *model.Download) {
+					d.ConflictOutcome = conflictOutcome(model.OutcomeFailed)
+				})
+				return fmt.Errorf("file already downloaded by download %s", same.ID)
+			}
+		}
+	}
+
+	// Once the temp file is started, its path must not change
+	if w.reservedPaths == nil || !w.download.HasFileName() || w.download.DownloadedBytes > 0 {
+		return nil
+	}
+	reserved, err := w.reservedPaths(w.download.ID)
+	if err != nil {
+		return err
+	}
+	finalPath, err := w.download.FinalFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to resolve final path: %w", err)
+	}
+	if !reserved[finalPath] {
+		return nil
+	}
+
+	log.Warnf("Download %s: %s is the final path of another download, applying policy %s", w.download.ID, finalPath, policy)
+	switch policy {
+	case model.ConflictRename:
+		fileName := filepath.Base(utils.AvailablePath(finalPath, func(p string) bool { return reserved[p] }))
+		w.UpdateDownload(func(d *model.Download) {
+			d.CustomFileName = &fileName
+			d.ConflictOutcome = conflictOutcome(model.OutcomeRenamed)
+		})
+		return nil
+	case model.ConflictSkip:
+		return errConflictSkipped
+	default:
+		// Both downloads would write the same temp file, even with OVERWRITE
+		w.UpdateDownload(func(d *model.Download) {
+			d.ConflictOutcome = conflictOutcome(model.OutcomeFailed)
+		})
+		return fmt.Errorf("file still being downloaded by another download: %s", finalPath)
+	}
+}
+
+// skip completes a duplicated download without downloading it.
+func (w *DownloadWorker) skip() error {
+	now := time.Now()
+	w.UpdateDownload(func(d *model.Download) {
+		d.ConflictOutcome = conflictOutcome(model.OutcomeSkipped)
+		d.Status = model.StatusCompleted
+		d.CompletedAt = &now
+		d.ErrorMessage = nil
+	})
+	w.notifyProgress()
+
+	log.Infof("Download %s skipped: duplicate of another download", w.download.ID)
+	return nil
+}
+
+// completedSameFile returns a completed download of the same file (checksum) as the
+// download, nil if there is none.
+func (m *DownloadManager) completedSameFile(download *model.Download) (*model.Download, error) {
+	if download.Checksum == nil || *download.Checksum == "" {
+		return nil, nil
+	}
+	sameChecksum, err := m.repo.GetByChecksum(*download.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check duplicates: %w", err)
+	}
+	for i := range sameChecksum {
+		if sameChecksum[i].ID != download.ID && sameChecksum[i].Status == model.StatusCompleted {
+			return &sameChecksum[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// reservedPaths returns the final paths of the unfinished downloads but downloadID.
+// The running workers are read from memory: their file info may not be written yet.
+func (m *DownloadManager) reservedPaths(downloadID string) (map[string]bool, error) {
+	unfinished, err := m.repo.GetUnfinished()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check duplicates: %w", err)
+	}
+
+	reserved := make(map[string]bool, len(unfinished))
+	reserve := func(download *model.Download) {
+		if download.ID == downloadID || download.Status.IsFinished() || !download.HasFileName() {
+			return
+		}
+		if finalPath, err := download.FinalFilePath(); err == nil {
+			reserved[finalPath] = true
+		}
+	}
+	for i := range unfinished {
+		if _, running := m.workers.Load(unfinished[i].ID); !running {
+			reserve(&unfinished[i])
+		}
+	}
+	m.workers.Range(func(_, value any) bool {
+		reserve(value.(*DownloadWorker).snapshot())
+		return true
+	})
+	return reserved, nil
+}
@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"context"
+	"dlbackend/internal/model"
+	"dlbackend/pkg/client"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadWorker_CheckConflicts(t *testing.T) {
+	setupTestConfig(t)
+
+	other := &model.Download{ID: "other", Status: model.StatusCompleted}
+	tests := []struct {
+		name        string
+		policy      model.ConflictPolicy
+		sameFile    *model.Download
+		reserved    bool
+		downloaded  int64
+		wantErr     string
+		wantSkipped bool
+		wantName    string
+		wantOutcome model.ConflictOutcome
+	}{
+		{name: "no conflict", policy: model.ConflictFail},
+		{name: "same file, rename", policy: model.ConflictRename, sameFile: other},
+		{name: "same file, overwrite", policy: model.ConflictOverwrite, sameFile: other},
+		{name: "same file, skip", policy: model.ConflictSkip, sameFile: other, wantSkipped: true},
+		{
+			name:        "same file, fail",
+			policy:      model.ConflictFail,
+			sameFile:    other,
+			wantErr:     "file already downloaded by download other",
+			wantOutcome: model.OutcomeFailed,
+		},
+		{
+			name:        "same path, rename",
+			policy:      model.ConflictRename,
+			reserved:    true,
+			wantName:    "movie (1).mkv",
+			wantOutcome: model.OutcomeRenamed,
+		},
+		{name: "same path, skip", policy: model.ConflictSkip, reserved: true, wantSkipped: true},
+		{
+			name:        "same path, overwrite",
+			policy:      model.ConflictOverwrite,
+			reserved:    true,
+			wantErr:     "file still being downloaded by another download",
+			wantOutcome: model.OutcomeFailed,
+		},
+		{
+			name:        "same path, fail",
+			policy:      model.ConflictFail,
+			reserved:    true,
+			wantErr:     "file still being downloaded by another download",
+			wantOutcome: model.OutcomeFailed,
+		},
+		{name: "same path, temp file started", policy: model.ConflictFail, reserved: true, downloaded: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			download := &model.Download{
+				ID:              "test-id",
+				FileName:        "movie.mkv",
+				DownloadedBytes: tt.downloaded,
+				Type:            model.TypeMovie,
+			}
+			worker := NewDownloadWorker(context.Background(), download, nil, new(MockProvider), nil)
+			worker.conflictPolicy = tt.policy
+			worker.sameFile = func(*model.Download) (*model.Download, error) { return tt.sameFile, nil }
+			worker.reservedPaths = func(downloadID string) (map[string]bool, error) {
+				assert.Equal(t, "test-id", downloadID)
+				finalPath, _ := download.FinalFilePath()
+				return map[string]bool{finalPath: tt.reserved}, nil
+			}
+
+			err := worker.checkConflicts()
+			switch {
+			case tt.wantSkipped:
+				assert.ErrorIs(t, err, errConflictSkipped)
+			case tt.wantErr != "":
+				assert.ErrorContains(t, err, tt.wantErr)
+			default:
+				assert.NoError(t, err)
+			}
+
+			if tt.wantName != "" {
+				require.NotNil(t, download.CustomFileName)
+				assert.Equal(t, tt.wantName, *download.CustomFileName)
+			} else {
+				assert.Nil(t, download.CustomFileName)
+			}
+			if tt.wantOutcome != "" {
+				require.NotNil(t, download.ConflictOutcome)
+				assert.Equal(t, tt.wantOutcome, *download.ConflictOutcome)
+			} else {
+				assert.Nil(t, download.ConflictOutcome)
+			}
+		})
+	}
+
+	t.Run("skipped download completes without downloading", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		mockSSE := new(MockSSEManager)
+		mockClient := new(MockProvider)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+		mockClient.On("GetFileInfo", "https://1fichier.com/?abc", "").Return(&client.FileInfo{
+			Filename: "movie.mkv",
+			Size:     1024,
+			Checksum: "abc123",
+		}, nil)
+
+		download := &model.Download{ID: "test-id", FileURL: "https://1fichier.com/?abc", Type: model.TypeMovie}
+		worker := NewDownloadWorker(context.Background(), download, mockRepo, mockClient, mockSSE)
+		worker.conflictPolicy = model.ConflictSkip
+		worker.sameFile = func(d *model.Download) (*model.Download, error) {
+			assert.Equal(t, "abc123", *d.Checksum)
+			return other, nil
+		}
+
+		require.NoError(t, worker.Run())
+		assert.Equal(t, model.StatusCompleted, download.Status)
+		require.NotNil(t, download.ConflictOutcome)
+		assert.Equal(t, model.OutcomeSkipped, *download.ConflictOutcome)
+		mockClient.AssertNotCalled(t, "ResolveDownloadURL", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDownloadManager_Conflicts(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("completed download of the same file", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		checksum := "abc123"
+		mockRepo.On("GetByChecksum", checksum).Return([]model.Download{
+			{ID: "test-id", Status: model.StatusCompleted},
+			{ID: "pending", Status: model.StatusPending},
+			{ID: "done", Status: model.StatusCompleted},
+		}, nil)
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, nil)
+
+		same, err := manager.completedSameFile(&model.Download{ID: "test-id", Checksum: &checksum})
+		require.NoError(t, err)
+		require.NotNil(t, same)
+		assert.Equal(t, "done", same.ID)
+
+		same, err = manager.completedSameFile(&model.Download{ID: "test-id"})
+		require.NoError(t, err)
+		assert.Nil(t, same)
+		mockRepo.AssertNumberOfCalls(t, "GetByChecksum", 1)
+	})
+
+	t.Run("reserved paths", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		mockRepo.On("GetUnfinished").Return([]model.Download{
+			{ID: "test-id", FileName: "self.mkv", Type: model.TypeMovie},
+			{ID: "queued", FileName: "queued.mkv", Type: model.TypeMovie},
+			{ID: "unknown", Type: model.TypeMovie},
+			{ID: "running", Type: model.TypeMovie}, // File info not written yet
+		}, nil)
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, nil)
+
+		running := &model.Download{ID: "running", FileName: "running.mkv", Status: model.StatusDownloading, Type: model.TypeMovie}
+		manager.workers.Store("running", NewDownloadWorker(context.Background(), running, mockRepo, new(MockProvider), nil))
+
+		reserved, err := manager.reservedPaths("test-id")
+		require.NoError(t, err)
+
+		path := func(name string) string {
+			finalPath, _ := (&model.Download{FileName: name, Type: model.TypeMovie}).FinalFilePath()
+			return finalPath
+		}
+		assert.Equal(t, map[string]bool{path("queued.mkv"): true, path("running.mkv"): true}, reserved)
+	})
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"dlbackend/internal/config"
+	"dlbackend/internal/model"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// DISK SPACE - Pre-flight check and low-space auto-pause
+// ============================================================================
+
+// ErrInsufficientDiskSpace is returned when a download does not fit on the disk.
+// The download goes back to the queue until enough space is freed.
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// remainingBytes returns the disk space the download still has to take, 0 if its size
+// is unknown. A preallocated temp file already holds its space, whatever was written.
+func remainingBytes(download *model.Download) int64 {
+	if download.FileSize == nil || *download.FileSize <= 0 {
+		return 0
+	}
+	allocated := download.DownloadedBytes
+	if tempPath, err := download.TempFilePath(); err == nil {
+		if stat, err := os.Stat(tempPath); err == nil {
+			allocated = max(allocated, stat.Size())
+		}
+	}
+	return max(*download.FileSize-allocated, 0)
+}
+
+// plannedBytes returns the disk space the scheduler sets aside for the download: its
+// remaining bytes, or the reserve when its size is unknown, as it may take as much.
+func plannedBytes(download *model.Download, reserve int64) int64 {
+	if download.FileSize == nil || *download.FileSize <= 0 {
+		return reserve
+	}
+	return remainingBytes(download)
+}
+
+// checkDiskSpace makes sure the rest of the file fits on the disk holding DLPath,
+// keeping the reserve free. The check is skipped if the free space can't be measured.
+func (w *DownloadWorker) checkDiskSpace() error {
+	need := remainingBytes(w.download)
+	if need == 0 {
+		return nil
+	}
+
+	free, err := w.freeSpace(config.Cfg.DLPath)
+	if err != nil {
+		log.Debugf("Disk space check skipped for %s: %v", w.download.ID, err)
+		return nil
+	}
+	if free-need < w.diskReserve {
+		return fmt.Errorf("%w: %d bytes needed, %d bytes free, %d bytes reserved", ErrInsufficientDiskSpace, need, free, w.diskReserve)
+	}
+	return nil
+}
+
+// requeue puts the download back in the queue, e.g. until enough disk space is freed.
+func (w *DownloadWorker) requeue(err error) error {
+	errMsg := err.Error()
+	w.UpdateDownload(func(d *model.Download) {
+		d.Status = model.StatusPending
+		d.ErrorMessage = &errMsg
+		d.Speed = nil
+		d.ETA = nil
+	})
+	w.notifyProgress()
+
+	log.Warnf("Download %s queued again: %v", w.download.ID, err)
+	return nil
+}
+
+// monitorDiskSpace pauses the running workers when the free space of the disk holding
+// DLPath drops below the reserve, and sends an SSE disk_low event. Preallocated workers
+// go on: their space is already taken. Paused workers are not resumed automatically:
+// the user resumes them once space is freed.
+// It returns the space left for new downloads: free space minus the reserve and the
+// bytes planned for the workers (see plannedBytes); ok is false if it can't be measured.
+// Only called from the scheduler goroutine.
+func (m *DownloadManager) monitorDiskSpace(reserve int64) (available int64, ok bool) {
+	free, err := m.freeSpace(config.Cfg.DLPath)
+	if err != nil {
+		log.Debugf("Disk space monitoring skipped: %v", err)
+		return 0, false
+	}
+
+	var committed int64
+	var paused []string
+	m.workers.Range(func(key, value any) bool {
+		worker := value.(*DownloadWorker)
+		if free < reserve && worker.State() == StateRunning && !worker.preallocated.Load() && worker.Pause() == nil {
+			paused = append(paused, key.(string))
+		}
+		committed += plannedBytes(worker.snapshot(), reserve)
+		return true
+	})
+
+	if len(paused) > 0 {
+		log.Warnf("Free disk space is low (%d bytes, %d reserved): %d downloads paused", free, reserve, len(paused))
+		event := model.DiskLowEvent{FreeSpace: free, Reserve: reserve, PausedIDs: paused}
+		if err := m.sseManager.SendEvent("disk_low", event); err != nil {
+			log.Errorf("Failed to send SSE for low disk space: %v", err)
+		}
+	}
+
+	return free - reserve - committed, true
+}
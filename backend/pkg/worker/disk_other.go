@@ -0,0 +1,10 @@
+//go:build !unix
+
+package worker
+
+import "errors"
+
+// diskFreeSpace is not supported on this platform: disk space checks are skipped.
+func diskFreeSpace(path string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,272 @@
+package worker
+
+import (
+	"context"
+	"dlbackend/internal/model"
+	"dlbackend/pkg/client"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedFreeSpace returns a freeSpace function reporting the given free space.
+func fixedFreeSpace(free int64) func(string) (int64, error) {
+	return func(string) (int64, error) { return free, nil }
+}
+
+func TestDownloadWorker_CheckDiskSpace(t *testing.T) {
+	setupTestConfig(t)
+
+	size := int64(1000)
+	tests := []struct {
+		name       string
+		fileSize   *int64
+		downloaded int64
+		freeSpace  func(string) (int64, error)
+		wantErr    bool
+	}{
+		{
+			name:      "fits with the reserve",
+			fileSize:  &size,
+			freeSpace: fixedFreeSpace(1100),
+		},
+		{
+			name:      "does not fit with the reserve",
+			fileSize:  &size,
+			freeSpace: fixedFreeSpace(1099),
+			wantErr:   true,
+		},
+		{
+			name:       "only the rest of the file is needed",
+			fileSize:   &size,
+			downloaded: 600,
+			freeSpace:  fixedFreeSpace(500),
+		},
+		{
+			name:      "unknown size",
+			freeSpace: fixedFreeSpace(0),
+		},
+		{
+			name:      "free space can't be measured",
+			fileSize:  &size,
+			freeSpace: func(string) (int64, error) { return 0, errors.ErrUnsupported },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			download := &model.Download{ID: "test-id", FileSize: tt.fileSize, DownloadedBytes: tt.downloaded}
+			worker := NewDownloadWorker(context.Background(), download, nil, nil, nil)
+			worker.diskReserve = 100
+			worker.freeSpace = tt.freeSpace
+
+			err := worker.checkDiskSpace()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInsufficientDiskSpace)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDownloadWorker_RequeueOnInsufficientDiskSpace(t *testing.T) {
+	setupTestConfig(t)
+
+	mockRepo := new(MockDownloadRepository)
+	mockClient := new(MockProvider)
+	mockSSE := new(MockSSEManager)
+	mockRepo.On("Update", mock.Anything).Return(nil)
+	mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+	mockClient.On("GetFileInfo", "https://1fichier.com/?test", "").Return(&client.FileInfo{
+		Filename: "test.mkv",
+		Size:     int64(1000),
+	}, nil)
+
+	download := &model.Download{ID: "test-id", FileURL: "https://1fichier.com/?test", Status: model.StatusPending, Type: model.TypeMovie}
+	worker := NewDownloadWorker(context.Background(), download, mockRepo, mockClient, mockSSE)
+	worker.freeSpace = fixedFreeSpace(500)
+
+	require.NoError(t, worker.Run())
+
+	assert.Equal(t, model.StatusPending, download.Status)
+	require.NotNil(t, download.ErrorMessage)
+	assert.Contains(t, *download.ErrorMessage, "insufficient disk space")
+	mockClient.AssertNotCalled(t, "ResolveDownloadURL", mock.Anything, mock.Anything)
+}
+
+func TestDownloadManager_MonitorDiskSpace(t *testing.T) {
+	setupTestConfig(t)
+
+	size := int64(1000)
+	newManager := func(free int64) (*DownloadManager, *DownloadWorker, *MockSSEManager) {
+		mockSSE := new(MockSSEManager)
+		mockSSE.On("SendEvent", "disk_low", mock.Anything).Return(nil)
+
+		manager := NewDownloadManager(context.Background(), nil, nil, nil, mockSSE)
+		manager.freeSpace = fixedFreeSpace(free)
+
+		running := NewDownloadWorker(context.Background(), &model.Download{ID: "running", FileSize: &size, DownloadedBytes: 400}, nil, nil, nil)
+		userPaused := NewDownloadWorker(context.Background(), &model.Download{ID: "user-paused"}, nil, nil, nil)
+		userPaused.Pause()
+		manager.workers.Store("running", running)
+		manager.workers.Store("user-paused", userPaused)
+
+		return manager, running, mockSSE
+	}
+
+	t.Run("enough space", func(t *testing.T) {
+		manager, running, mockSSE := newManager(10_000)
+
+		available, ok := manager.monitorDiskSpace(1_000)
+		require.True(t, ok)
+		// The user paused download of unknown size may take the reserve
+		assert.Equal(t, int64(10_000-1_000-600-1_000), available)
+		assert.False(t, running.IsPaused())
+		mockSSE.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything)
+	})
+
+	t.Run("low space pauses running downloads", func(t *testing.T) {
+		manager, running, mockSSE := newManager(999)
+
+		available, ok := manager.monitorDiskSpace(1_000)
+		require.True(t, ok)
+		assert.Negative(t, available)
+		assert.True(t, running.IsPaused())
+		mockSSE.AssertCalled(t, "SendEvent", "disk_low", model.DiskLowEvent{
+			FreeSpace: 999,
+			Reserve:   1_000,
+			PausedIDs: []string{"running"},
+		})
+
+		// Nothing left to pause: no new event
+		manager.monitorDiskSpace(1_000)
+		mockSSE.AssertNumberOfCalls(t, "SendEvent", 1)
+	})
+
+	t.Run("low space keeps preallocated downloads running", func(t *testing.T) {
+		manager, running, mockSSE := newManager(999)
+		running.preallocated.Store(true)
+
+		_, ok := manager.monitorDiskSpace(1_000)
+		require.True(t, ok)
+		assert.False(t, running.IsPaused())
+		mockSSE.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything)
+	})
+
+	t.Run("free space can't be measured", func(t *testing.T) {
+		manager, running, _ := newManager(0)
+		manager.freeSpace = func(string) (int64, error) { return 0, errors.ErrUnsupported }
+
+		_, ok := manager.monitorDiskSpace(1_000)
+		assert.False(t, ok)
+		assert.False(t, running.IsPaused())
+	})
+}
+
+func TestDownloadManager_ScheduleDiskSpace(t *testing.T) {
+	setupTestConfig(t)
+
+	big, small := int64(5_000), int64(500)
+	tests := []struct {
+		name        string
+		free        int64
+		pending     []model.Download
+		wantStarted []string
+		wantWaiting []string
+		wantNoQueue bool // The queue is not even read
+	}{
+		{
+			name: "big download waits, small one starts",
+			free: 2_000,
+			pending: []model.Download{
+				{ID: "big", FileSize: &big},
+				{ID: "small", FileSize: &small},
+			},
+			wantStarted: []string{"small"},
+			wantWaiting: []string{"big"},
+		},
+		{
+			name: "unknown size needs the reserve",
+			free: 2_500,
+			pending: []model.Download{
+				{ID: "unknown-1"},
+				{ID: "unknown-2"},
+			},
+			wantStarted: []string{"unknown-1"},
+			wantWaiting: []string{"unknown-2"},
+		},
+		{
+			name: "below the reserve",
+			free: 900,
+			pending: []model.Download{
+				{ID: "unknown"},
+			},
+			wantWaiting: []string{"unknown"},
+			wantNoQueue: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockDownloadRepository)
+			mockSettingsRepo := new(MockSettingsRepository)
+			mockSSE := new(MockSSEManager)
+			mockClient := new(MockProvider)
+
+			mockSettingsRepo.On("Get").Return(&model.Settings{
+				APIKey1fichier:         "test-api-key",
+				MaxConcurrentDownloads: 2,
+				DiskReserve:            1_000,
+			}, nil)
+			for i := range tt.pending {
+				tt.pending[i].Status = model.StatusPending
+				tt.pending[i].Type = model.TypeMovie
+				mockRepo.On("GetByID", tt.pending[i].ID).Return(&tt.pending[i], nil)
+			}
+			mockRepo.On("GetPending").Return(tt.pending, nil)
+			mockRepo.On("Update", mock.Anything).Return(nil)
+			mockSSE.On("SendEvent", mock.Anything, mock.Anything).Return(nil)
+			mockClient.On("GetFileInfo", mock.Anything, mock.Anything).Return(nil, errors.New("offline"))
+
+			manager := NewDownloadManager(context.Background(), mockRepo, mockSettingsRepo, client.Providers{mockClient}, mockSSE)
+			manager.freeSpace = fixedFreeSpace(tt.free)
+
+			manager.schedule()
+			for _, id := range tt.wantStarted {
+				waitForWorkerCompletion(t, manager, id, 5*time.Second)
+				mockRepo.AssertCalled(t, "Update", mock.MatchedBy(func(d *model.Download) bool { return d.ID == id }))
+			}
+			for _, id := range tt.wantWaiting {
+				mockRepo.AssertNotCalled(t, "Update", mock.MatchedBy(func(d *model.Download) bool { return d.ID == id }))
+			}
+			if tt.wantNoQueue {
+				mockRepo.AssertNotCalled(t, "GetPending")
+			}
+		})
+	}
+	t.Run("paused download waiting for a slot stays paused below the reserve", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSSE := new(MockSSEManager)
+		mockSettingsRepo.On("Get").Return(&model.Settings{MaxConcurrentDownloads: 2, DiskReserve: 1_000}, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", mock.Anything, mock.Anything).Return(nil)
+
+		manager := NewDownloadManager(context.Background(), mockRepo, mockSettingsRepo, nil, mockSSE)
+		manager.freeSpace = fixedFreeSpace(900)
+		queued := NewDownloadWorker(context.Background(), &model.Download{ID: "queued", FileSize: &small}, mockRepo, nil, mockSSE)
+		queued.Pause()
+		manager.workers.Store("queued", queued)
+		manager.queueResume(queued)
+
+		manager.schedule()
+		assert.True(t, queued.IsPaused())
+		assert.Equal(t, []string{"queued"}, manager.resumeQueue)
+		mockRepo.AssertNotCalled(t, "GetPending")
+	})
+}
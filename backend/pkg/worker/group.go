@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// DOWNLOAD GROUPS - Aggregate progress events
+// ============================================================================
+
+// groupProgressInterval is the period at which group progress events are sent.
+// Progress of the downloads of a group is coalesced in between.
+const groupProgressInterval = time.Second
+
+// markGroupDirty records that the progress of the download's group changed.
+func (m *DownloadManager) markGroupDirty(download *model.Download) {
+	if download.GroupID == nil {
+		return
+	}
+
+	m.groupsMu.Lock()
+	defer m.groupsMu.Unlock()
+	m.dirtyGroups[*download.GroupID] = struct{}{}
+}
+
+// runGroupProgress sends the group progress events until the manager context is done.
+func (m *DownloadManager) runGroupProgress() {
+	ticker := time.NewTicker(groupProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.flushGroupProgress()
+		}
+	}
+}
+
+// flushGroupProgress sends an SSE group_progress event for every group changed
+// since the last flush. Running downloads use the live worker state.
+func (m *DownloadManager) flushGroupProgress() {
+	m.groupsMu.Lock()
+	dirty := m.dirtyGroups
+	m.dirtyGroups = make(map[string]struct{})
+	m.groupsMu.Unlock()
+
+	for groupID := range dirty {
+		downloads, err := m.repo.GetByGroupID(groupID)
+		if err != nil {
+			log.Errorf("Failed to get downloads of group %s: %v", groupID, err)
+			continue
+		}
+		for i := range downloads {
+			if value, ok := m.workers.Load(downloads[i].ID); ok {
+				downloads[i] = *value.(*DownloadWorker).snapshot()
+			}
+		}
+
+		event := model.DownloadGroupProgressEvent{
+			GroupID:               groupID,
+			DownloadGroupProgress: model.NewDownloadGroupProgress(downloads),
+		}
+		if err := m.sseManager.SendEvent("group_progress", event); err != nil {
+			log.Errorf("Failed to send SSE for group %s: %v", groupID, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"dlbackend/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadManager_GroupProgress(t *testing.T) {
+	setupTestConfig(t)
+
+	groupID := "group-id"
+	size := int64(1000)
+	speed := float64(100)
+
+	mockRepo := new(MockDownloadRepository)
+	mockSSE := new(MockSSEManager)
+
+	// The DB is behind the running worker
+	mockRepo.On("GetByGroupID", groupID).Return([]model.Download{
+		{ID: "completed", GroupID: &groupID, Status: model.StatusCompleted, FileSize: &size, DownloadedBytes: size},
+		{ID: "running", GroupID: &groupID, Status: model.StatusDownloading, FileSize: &size, DownloadedBytes: 100},
+		{ID: "cancelled", GroupID: &groupID, Status: model.StatusCancelled},
+	}, nil)
+
+	var event model.DownloadGroupProgressEvent
+	mockSSE.On("SendEvent", "group_progress", mock.Anything).Run(func(args mock.Arguments) {
+		event = args.Get(1).(model.DownloadGroupProgressEvent)
+	}).Return(nil)
+
+	manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, mockSSE)
+	running := &model.Download{ID: "running", GroupID: &groupID, Status: model.StatusDownloading, FileSize: &size, DownloadedBytes: 500, Speed: &speed}
+	manager.workers.Store("running", NewDownloadWorker(context.Background(), running, mockRepo, nil, mockSSE))
+
+	t.Run("nothing to send", func(t *testing.T) {
+		manager.markGroupDirty(&model.Download{ID: "single"})
+		manager.flushGroupProgress()
+		mockSSE.AssertNotCalled(t, "SendEvent", "group_progress", mock.Anything)
+	})
+
+	t.Run("aggregate progress", func(t *testing.T) {
+		manager.markGroupDirty(running)
+		manager.markGroupDirty(running)
+		manager.flushGroupProgress()
+
+		mockSSE.AssertNumberOfCalls(t, "SendEvent", 1)
+		require.Equal(t, groupID, event.GroupID)
+		assert.Equal(t, 3, event.Total)
+		assert.Equal(t, 1, event.Active)
+		assert.Equal(t, 1, event.Completed)
+		assert.Equal(t, 1, event.Failed)
+		assert.Equal(t, int64(2000), event.FileSize)
+		assert.Equal(t, int64(1500), event.DownloadedBytes)
+		assert.Equal(t, float64(75), event.Progress)
+		assert.Equal(t, speed, event.Speed)
+	})
+
+	t.Run("coalesced until the next change", func(t *testing.T) {
+		manager.flushGroupProgress()
+		mockSSE.AssertNumberOfCalls(t, "SendEvent", 1)
+	})
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package worker
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// preallocate reserves size bytes on disk for the file with fallocate, keeping its
+// content, and reports whether the blocks are reserved. Filesystems without fallocate
+// support fall back to truncate: the file is sparse until written.
+func preallocate(file *os.File, size int64) (reserved bool, err error) {
+	err = syscall.Fallocate(int(file.Fd()), 0, 0, size)
+	if err == nil || errors.Is(err, syscall.ENOSPC) {
+		return err == nil, err
+	}
+	return false, file.Truncate(size)
+}
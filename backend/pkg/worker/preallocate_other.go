@@ -0,0 +1,11 @@
+//go:build !linux
+
+package worker
+
+import "os"
+
+// preallocate extends the file to size bytes. Without fallocate the blocks are not
+// reserved: the file is sparse until written.
+func preallocate(file *os.File, size int64) (reserved bool, err error) {
+	return false, file.Truncate(size)
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadWorker_Preallocate(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("new file takes the expected size", func(t *testing.T) {
+		worker := newTestWorker(t, withSize(4096))
+
+		require.NoError(t, worker.prepareFile())
+		worker.closeFile()
+
+		tempPath, _ := worker.download.TempFilePath()
+		defer os.Remove(tempPath)
+		stat, err := os.Stat(tempPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4096), stat.Size())
+		assert.Zero(t, worker.download.DownloadedBytes)
+		assert.True(t, tempFileMatches(worker.download))
+	})
+
+	t.Run("resume writes at the downloaded offset", func(t *testing.T) {
+		// Preallocated file: the tail is zeroed, only the first 6 bytes are downloaded
+		downloadURL := "https://download.1fichier.com/test"
+		worker := newTestWorker(t,
+			withSize(11),
+			withContent("hello "+string(make([]byte, 5))),
+			withDownloaded(6),
+			withDownloadURL(downloadURL),
+		)
+		require.True(t, tempFileMatches(worker.download))
+
+		worker.mockClient.On("DownloadFile", downloadURL, int64(6)).Return(
+			&MockReadCloser{reader: strings.NewReader("world")}, int64(5), http.StatusPartialContent, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		completed, err := worker.downloadChunk()
+		worker.closeFile()
+		require.NoError(t, err)
+		require.True(t, completed)
+
+		content, err := os.ReadFile(worker.tempPath)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(content))
+	})
+
+	t.Run("completion refuses a file shorter than announced", func(t *testing.T) {
+		// The server announced more than it sent
+		worker := newTestWorker(t, withSize(4096))
+		require.NoError(t, worker.prepareFile())
+		_, err := worker.file.WriteAt([]byte("short"), 0)
+		require.NoError(t, err)
+		worker.closeFile()
+		worker.download.DownloadedBytes = 5
+
+		require.Error(t, worker.complete())
+		assert.Equal(t, model.StatusFailed, worker.download.Status)
+
+		// The temp file is kept for a later attempt
+		finalPath, _ := worker.download.FinalFilePath()
+		tempPath, _ := worker.download.TempFilePath()
+		defer os.Remove(tempPath)
+		assert.NoFileExists(t, finalPath)
+		assert.FileExists(t, tempPath)
+	})
+}
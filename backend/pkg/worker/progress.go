@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"dlbackend/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// PROGRESS PERSISTENCE - Batched DB writes of running downloads
+// ============================================================================
+
+// progressFlushInterval is the period at which buffered progress is written to the DB.
+const progressFlushInterval = 2 * time.Second
+
+// progressWriter persists the state of the running downloads. Progress ticks are
+// coalesced per download and written on an interval; state transitions are written
+// immediately and replace the buffered progress of the download. Once closed, all
+// the snapshots are written immediately.
+type progressWriter struct {
+	repo    repository.DownloadRepository
+	pending map[string]*model.Download // Latest buffered snapshot per download
+	closed  bool
+	mu      sync.Mutex // Held during writes, so an old snapshot never overwrites a newer one
+}
+
+func newProgressWriter(repo repository.DownloadRepository) *progressWriter {
+	return &progressWriter{
+		repo:    repo,
+		pending: make(map[string]*model.Download),
+	}
+}
+
+// queue buffers the snapshot until the next flush, replacing the previous one.
+func (p *progressWriter) queue(download *model.Download) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		if err := p.repo.Update(download); err != nil {
+			log.Errorf("Failed to update DB for download %s: %v", download.ID, err)
+		}
+		return
+	}
+	p.pending[download.ID] = download
+}
+
+// write persists the snapshot now, dropping the buffered one.
+func (p *progressWriter) write(download *model.Download) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, download.ID)
+	return p.repo.Update(download)
+}
+
+// flush persists all the buffered snapshots.
+func (p *progressWriter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.flushLocked()
+}
+
+// close flushes the buffered snapshots and stops buffering the next ones.
+func (p *progressWriter) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.flushLocked()
+}
+
+// flushLocked persists all the buffered snapshots. Must be called with mu held.
+func (p *progressWriter) flushLocked() {
+	for id, download := range p.pending {
+		if err := p.repo.Update(download); err != nil {
+			log.Errorf("Failed to update DB for download %s: %v", id, err)
+		}
+		delete(p.pending, id)
+	}
+}
+
+// Close persists the buffered progress of the running downloads, before the database
+// is closed on shutdown. The next progress updates are written immediately.
+func (m *DownloadManager) Close() {
+	m.progress.close()
+}
+
+// runProgressWriter flushes the buffered progress until the manager context is done,
+// then flushes one last time.
+func (m *DownloadManager) runProgressWriter() {
+	ticker := time.NewTicker(progressFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			m.progress.flush()
+			return
+		case <-ticker.C:
+			m.progress.flush()
+		}
+	}
+}
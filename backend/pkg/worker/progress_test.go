@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"dlbackend/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadWorker_ProgressPersistence(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("progress ticks are coalesced until the flush", func(t *testing.T) {
+		var written []model.Download
+		worker := newTestWorker(t, recordWrites(&written))
+		worker.progress = newProgressWriter(worker.mockRepo)
+
+		for _, downloaded := range []int64{100, 200, 300} {
+			worker.UpdateDownload(func(d *model.Download) { d.DownloadedBytes = downloaded })
+			worker.reportProgress()
+		}
+		worker.mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+		worker.mockSSE.AssertNumberOfCalls(t, "SendEvent", 3)
+
+		worker.progress.flush()
+		require.Len(t, written, 1)
+		assert.Equal(t, int64(300), written[0].DownloadedBytes)
+
+		// Nothing left to write
+		worker.progress.flush()
+		assert.Len(t, written, 1)
+	})
+
+	t.Run("state transitions are written immediately", func(t *testing.T) {
+		var written []model.Download
+		worker := newTestWorker(t, recordWrites(&written))
+		worker.progress = newProgressWriter(worker.mockRepo)
+
+		worker.UpdateDownload(func(d *model.Download) { d.DownloadedBytes = 100 })
+		worker.reportProgress()
+		worker.UpdateDownload(func(d *model.Download) { d.Status = model.StatusPaused })
+		worker.notifyProgress()
+
+		require.Len(t, written, 1)
+		assert.Equal(t, model.StatusPaused, written[0].Status)
+
+		// The buffered tick is dropped: it would overwrite the transition
+		worker.progress.flush()
+		assert.Len(t, written, 1)
+	})
+
+	t.Run("without writer every update is written", func(t *testing.T) {
+		var written []model.Download
+		worker := newTestWorker(t, recordWrites(&written))
+		worker.progress = newProgressWriter(worker.mockRepo)
+		worker.progress = nil
+
+		worker.reportProgress()
+		worker.notifyProgress()
+		assert.Len(t, written, 2)
+	})
+}
+
+func TestDownloadManager_Close(t *testing.T) {
+	mockRepo := new(MockDownloadRepository)
+	mockSSE := new(MockSSEManager)
+	var written []model.Download
+	mockRepo.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		written = append(written, *args.Get(0).(*model.Download))
+	}).Return(nil)
+	mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+	manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, mockSSE)
+	worker := NewDownloadWorker(context.Background(), &model.Download{ID: "test-id", Status: model.StatusDownloading}, mockRepo, new(MockProvider), mockSSE)
+	worker.progress = manager.progress
+
+	worker.UpdateDownload(func(d *model.Download) { d.DownloadedBytes = 100 })
+	worker.reportProgress()
+	require.Empty(t, written)
+
+	// Shutdown: the buffered snapshot is written
+	manager.Close()
+	require.Len(t, written, 1)
+	assert.Equal(t, int64(100), written[0].DownloadedBytes)
+
+	// Progress reported after the shutdown is written immediately
+	worker.UpdateDownload(func(d *model.Download) { d.DownloadedBytes = 200 })
+	worker.reportProgress()
+	require.Len(t, written, 2)
+	assert.Equal(t, int64(200), written[1].DownloadedBytes)
+}
@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"context"
+	"dlbackend/internal/config"
+	"dlbackend/internal/model"
+	"dlbackend/pkg/client"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// RETRY POLICY
+// ============================================================================
+
+// RetryPolicy defines how transient download failures are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of consecutive retries without progress (0 disables retries)
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on each following attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two retries
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy from the application configuration.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: config.Cfg.RetryMaxAttempts,
+		BaseDelay:   config.Cfg.RetryBaseDelay,
+		MaxDelay:    config.Cfg.RetryMaxDelay,
+	}
+}
+
+// Backoff returns the delay before the given retry attempt (starting at 1).
+// The delay grows exponentially up to MaxDelay, with a random jitter on its
+// upper half so that concurrent workers don't reconnect at the same time.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// isTransient reports whether the error is worth a retry: network errors, HTTP 5xx
+// responses and the 1fichier flood protection. Other API errors (bad API key, deleted
+// file...) are permanent.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, client.ErrRateLimited) {
+		return true
+	}
+	if errors.Is(err, client.ErrPrivateAddress) {
+		return false
+	}
+
+	var statusErr *client.DownloadStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError ||
+			statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// withRetry runs the step and retries it on transient failures according to the retry policy.
+// The attempt counter is reset whenever the step downloaded data since the previous failure,
+// so a long download is not failed by a few unrelated connection drops.
+func (w *DownloadWorker) withRetry(step func() error) error {
+	attempt := 0
+	offset := w.download.DownloadedBytes
+
+	for {
+		err := step()
+		if err == nil || w.IsCancelled() || !isTransient(err) {
+			return err
+		}
+
+		if w.download.DownloadedBytes > offset {
+			attempt = 0
+			offset = w.download.DownloadedBytes
+		}
+		if attempt >= w.retryPolicy.MaxAttempts {
+			return err
+		}
+		attempt++
+
+		delay := w.retryPolicy.Backoff(attempt)
+		w.notifyRetry(err, attempt, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// notifyRetry records the retry on the download and broadcasts an SSE retry event.
+func (w *DownloadWorker) notifyRetry(err error, attempt int, delay time.Duration) {
+	errMsg := err.Error()
+	w.UpdateDownload(func(d *model.Download) {
+		d.ErrorMessage = &errMsg
+		d.RetryCount++
+		d.Speed = nil
+		d.ETA = nil
+	})
+	w.notifyProgress()
+
+	event := model.DownloadRetryEvent{
+		DownloadID:  w.download.ID,
+		Attempt:     attempt,
+		MaxAttempts: w.retryPolicy.MaxAttempts,
+		DelayMs:     delay.Milliseconds(),
+		Error:       errMsg,
+	}
+	if err := w.sseManager.SendEvent("retry", event); err != nil {
+		log.Errorf("Failed to send SSE for download %s: %v", w.download.ID, err)
+	}
+
+	log.Warnf("Download %s failed (%v), retry %d/%d in %s", w.download.ID, err, attempt, w.retryPolicy.MaxAttempts, delay)
+}
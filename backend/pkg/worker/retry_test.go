@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"dlbackend/internal/model"
+	"dlbackend/pkg/client"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: 100 * time.Millisecond},
+		{attempt: 2, max: 200 * time.Millisecond},
+		{attempt: 3, max: 400 * time.Millisecond},
+		{attempt: 4, max: 800 * time.Millisecond},
+		{attempt: 5, max: time.Second},
+		{attempt: 50, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			for range 20 {
+				delay := policy.Backoff(tt.attempt)
+				assert.GreaterOrEqual(t, delay, tt.max/2)
+				assert.LessOrEqual(t, delay, tt.max)
+			}
+		})
+	}
+
+	t.Run("zero policy", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), RetryPolicy{}.Backoff(1))
+	})
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "server error", err: &client.DownloadStatusError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "too many requests", err: &client.DownloadStatusError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "not found", err: &client.DownloadStatusError{StatusCode: http.StatusNotFound}, want: false},
+		{name: "wrapped server error", err: fmt.Errorf("failed to start download: %w", &client.DownloadStatusError{StatusCode: 503}), want: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("timeout")}, want: true},
+		{name: "context canceled", err: context.Canceled, want: false},
+		{name: "api error", err: errors.New("403 failed to get fileinfo"), want: false},
+		{name: "flood protection", err: fmt.Errorf("failed to get token: %w", client.NewAPIError(http.StatusForbidden, "Flood detected: IP Locked #38")), want: true},
+		{name: "file not found", err: client.NewAPIError(http.StatusForbidden, "Resource not found #469"), want: false},
+		{name: "api key rejected", err: client.NewAPIError(http.StatusUnauthorized, ""), want: false},
+		{name: "private network address", err: &net.OpError{Op: "dial", Err: fmt.Errorf("%w: 127.0.0.1", client.ErrPrivateAddress)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTransient(tt.err))
+		})
+	}
+}
+
+func TestDownloadWorker_WithRetry(t *testing.T) {
+	setupTestConfig(t)
+
+	fastRetries := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	t.Run("transient error is retried", func(t *testing.T) {
+		worker := newTestWorker(t)
+		worker.retryPolicy = fastRetries
+
+		calls := 0
+		err := worker.withRetry(func() error {
+			calls++
+			if calls < 3 {
+				return &client.DownloadStatusError{StatusCode: http.StatusServiceUnavailable}
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 2, worker.download.RetryCount)
+		worker.mockSSE.AssertNumberOfCalls(t, "SendEvent", 4) // progress + retry, per retry
+		worker.mockSSE.AssertCalled(t, "SendEvent", "retry", mock.MatchedBy(func(e model.DownloadRetryEvent) bool {
+			return e.DownloadID == "test-id" && e.MaxAttempts == 3
+		}))
+	})
+
+	t.Run("permanent error fails immediately", func(t *testing.T) {
+		worker := newTestWorker(t)
+		worker.retryPolicy = fastRetries
+
+		calls := 0
+		err := worker.withRetry(func() error {
+			calls++
+			return errors.New("403 failed to get fileinfo")
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, 0, worker.download.RetryCount)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		worker := newTestWorker(t)
+		worker.retryPolicy = fastRetries
+
+		calls := 0
+		err := worker.withRetry(func() error {
+			calls++
+			return io.ErrUnexpectedEOF
+		})
+
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("progress resets attempts", func(t *testing.T) {
+		worker := newTestWorker(t)
+		worker.retryPolicy = fastRetries
+
+		calls := 0
+		err := worker.withRetry(func() error {
+			calls++
+			if calls < 6 {
+				worker.download.DownloadedBytes += 10
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 6, calls)
+	})
+
+	t.Run("cancel stops retrying", func(t *testing.T) {
+		worker := newTestWorker(t)
+		worker.retryPolicy = fastRetries
+		worker.retryPolicy.BaseDelay = time.Hour
+		worker.retryPolicy.MaxDelay = time.Hour
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			worker.Cancel()
+		}()
+
+		err := worker.withRetry(func() error {
+			return io.ErrUnexpectedEOF
+		})
+		assert.Error(t, err)
+		assert.True(t, worker.IsCancelled())
+	})
+}
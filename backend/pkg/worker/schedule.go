@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"reflect"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// DOWNLOAD SCHEDULE - Time windows pausing or throttling downloads
+// ============================================================================
+
+// scheduleAt resolves the schedule state at the given time.
+// The first matching rule wins; its speed cap can only lower the global limit.
+func scheduleAt(settings *model.Settings, now time.Time) model.ScheduleEvent {
+	state := model.ScheduleEvent{SpeedLimit: settings.SpeedLimit}
+
+	for i := range settings.Schedule {
+		rule := settings.Schedule[i]
+		if !rule.Matches(now) {
+			continue
+		}
+
+		state.Rule = &rule
+		state.Paused = rule.Paused
+		if !rule.Paused && rule.SpeedLimit > 0 && (state.SpeedLimit == 0 || rule.SpeedLimit < state.SpeedLimit) {
+			state.SpeedLimit = rule.SpeedLimit
+		}
+		break
+	}
+
+	return state
+}
+
+// applySchedule applies the schedule state to the workers: it adjusts the global
+// bandwidth limit, and pauses running workers during a paused window. Workers
+// paused by the schedule are queued to resume when the window ends, as slots are
+// free; downloads paused by the user, even during the window, are left untouched.
+// Only called from the scheduler goroutine.
+func (m *DownloadManager) applySchedule(state model.ScheduleEvent) {
+	m.limiter.SetRate(state.SpeedLimit)
+
+	m.scheduleMu.Lock()
+	var resumed []string
+	if state.Paused {
+		m.workers.Range(func(key, value any) bool {
+			worker := value.(*DownloadWorker)
+			if worker.State() == StateRunning && worker.Pause() == nil {
+				m.schedulePaused[key.(string)] = struct{}{}
+			}
+			return true
+		})
+	} else {
+		for id := range m.schedulePaused {
+			resumed = append(resumed, id)
+		}
+		clear(m.schedulePaused)
+	}
+	changed := !reflect.DeepEqual(m.scheduleState, state)
+	m.scheduleState = state
+	m.scheduleMu.Unlock()
+
+	// Resumed by the scheduler within the free slots, the limit may have changed meanwhile
+	for _, id := range resumed {
+		if value, ok := m.workers.Load(id); ok {
+			m.queueResume(value.(*DownloadWorker))
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	log.Infof("Download schedule changed: paused=%t speedLimit=%d", state.Paused, state.SpeedLimit)
+	if err := m.sseManager.SendEvent("schedule", state); err != nil {
+		log.Errorf("Failed to send SSE for download schedule: %v", err)
+	}
+}
+
+// keepPaused forgets that the schedule paused the download: paused by the user, it
+// is not resumed at the end of the window.
+func (m *DownloadManager) keepPaused(downloadID string) {
+	m.scheduleMu.Lock()
+	defer m.scheduleMu.Unlock()
+	delete(m.schedulePaused, downloadID)
+}
+
+// ScheduleState returns the schedule state currently applied to downloads (thread-safe).
+func (m *DownloadManager) ScheduleState() model.ScheduleEvent {
+	m.scheduleMu.Lock()
+	defer m.scheduleMu.Unlock()
+	return m.scheduleState
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"dlbackend/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleAt(t *testing.T) {
+	// Monday
+	at := func(clock string) time.Time {
+		date, _ := time.ParseInLocation("2006-01-02 15:04", "2026-10-12 "+clock, time.Local)
+		return date
+	}
+
+	settings := &model.Settings{
+		SpeedLimit: 10_000,
+		Schedule: []model.ScheduleRule{
+			{Days: []time.Weekday{time.Monday}, Start: "08:00", End: "18:00", Paused: true},
+			{Days: []time.Weekday{time.Sunday}, Start: "22:00", End: "02:00", SpeedLimit: 1_000},
+			{Start: "20:00", End: "22:00", SpeedLimit: 50_000},
+		},
+	}
+
+	t.Run("outside of every window", func(t *testing.T) {
+		state := scheduleAt(settings, at("07:59"))
+		assert.False(t, state.Paused)
+		assert.Nil(t, state.Rule)
+		assert.Equal(t, int64(10_000), state.SpeedLimit)
+	})
+
+	t.Run("paused window", func(t *testing.T) {
+		state := scheduleAt(settings, at("08:00"))
+		assert.True(t, state.Paused)
+		require.NotNil(t, state.Rule)
+		assert.Equal(t, "08:00", state.Rule.Start)
+	})
+
+	t.Run("end is exclusive", func(t *testing.T) {
+		assert.False(t, scheduleAt(settings, at("18:00")).Paused)
+	})
+
+	t.Run("window spanning midnight from the previous day", func(t *testing.T) {
+		state := scheduleAt(settings, at("01:30"))
+		assert.False(t, state.Paused)
+		assert.Equal(t, int64(1_000), state.SpeedLimit)
+	})
+
+	t.Run("window can't raise the global limit", func(t *testing.T) {
+		state := scheduleAt(settings, at("21:00"))
+		require.NotNil(t, state.Rule)
+		assert.Equal(t, int64(10_000), state.SpeedLimit)
+	})
+}
+
+func TestDownloadManager_ApplySchedule(t *testing.T) {
+	setupTestConfig(t)
+
+	mockRepo := new(MockDownloadRepository)
+	mockSSE := new(MockSSEManager)
+	mockRepo.On("Update", mock.Anything).Return(nil)
+	mockSSE.On("SendEvent", "schedule", mock.Anything).Return(nil)
+	mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+	manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, mockSSE)
+
+	running := NewDownloadWorker(context.Background(), &model.Download{ID: "running"}, mockRepo, nil, mockSSE)
+	userPaused := NewDownloadWorker(context.Background(), &model.Download{ID: "user-paused"}, mockRepo, nil, mockSSE)
+	userPaused.Pause()
+	manager.workers.Store("running", running)
+	manager.workers.Store("user-paused", userPaused)
+
+	paused := model.ScheduleEvent{Paused: true, Rule: &model.ScheduleRule{Start: "08:00", End: "18:00", Paused: true}}
+	manager.applySchedule(paused)
+	assert.True(t, running.IsPaused())
+	assert.Equal(t, paused, manager.ScheduleState())
+
+	// Same state: no new event
+	manager.applySchedule(paused)
+	mockSSE.AssertNumberOfCalls(t, "SendEvent", 1)
+
+	// End of the window: the worker waits for a slot, the scheduler resumes it
+	manager.applySchedule(model.ScheduleEvent{SpeedLimit: 1_000})
+	assert.Equal(t, []string{"running"}, manager.resumeQueue)
+	assert.Equal(t, int64(1_000), manager.limiter.Rate())
+	mockSSE.AssertNumberOfCalls(t, "SendEvent", 3) // Schedule change, and the worker shown as pending
+	assert.Equal(t, model.StatusPending, running.download.Status)
+	assert.Equal(t, 1, manager.resumeQueued(1))
+	assert.False(t, running.IsPaused())
+	assert.True(t, userPaused.IsPaused())
+
+	t.Run("paused by the user during the window", func(t *testing.T) {
+		manager.applySchedule(paused)
+		require.True(t, running.IsPaused())
+
+		require.NoError(t, manager.Pause("running"))
+		manager.applySchedule(model.ScheduleEvent{})
+		assert.Empty(t, manager.resumeQueue)
+		assert.Equal(t, 0, manager.resumeQueued(1))
+		assert.True(t, running.IsPaused())
+	})
+}
@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// ============================================================================
+// SEGMENTED DOWNLOAD - Multiple connections, one byte range each
+// ============================================================================
+
+// minSegmentSize is the smallest byte range worth its own connection.
+const minSegmentSize int64 = 16 << 20 // 16 MiB
+
+// errRangeNotSupported is returned when the server ignores the Range header of a segment.
+var errRangeNotSupported = errors.New("server does not support range requests")
+
+// splitSegments divides size bytes into at most count contiguous byte ranges.
+// It returns nil when the file is too small to be split.
+func splitSegments(size int64, count int) []model.DownloadSegment {
+	count = int(min(int64(count), size/minSegmentSize))
+	if count < 2 {
+		return nil
+	}
+
+	segmentSize := size / int64(count)
+	segments := make([]model.DownloadSegment, count)
+	for i := range segments {
+		segments[i].Start = int64(i) * segmentSize
+		segments[i].End = segments[i].Start + segmentSize - 1
+	}
+	segments[count-1].End = size - 1 // The last segment takes the remainder
+
+	return segments
+}
+
+// downloadSegments downloads the remaining byte ranges in parallel until all
+// of them are complete, or until pause, cancel or the first segment error.
+// Progress and speed are the aggregate of all segments.
+func (w *DownloadWorker) downloadSegments() (completed bool, err error) {
+	var wg sync.WaitGroup
+	var stop atomic.Bool // Set on the first error to stop the other segments
+	errs := make(chan error, len(w.download.Segments))
+
+	for i, segment := range w.download.Segments {
+		if segment.Done() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.downloadSegment(i, &stop); err != nil {
+				stop.Store(true)
+				errs <- err
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Update speed and checkpoint periodically while segments are running
+	lastUpdate := time.Now()
+	lastBytes := w.snapshot().DownloadedBytes
+	lastCheckpoint := time.Now()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case <-ticker.C:
+			w.updateSpeed(&lastUpdate, &lastBytes)
+			if time.Since(lastCheckpoint) >= checkpointInterval {
+				w.saveSegmentsCheckpoint()
+				lastCheckpoint = time.Now()
+			}
+		}
+	}
+	w.updateSpeed(&lastUpdate, &lastBytes)
+
+	// Record the offsets reached, whatever the reason to stop
+	w.saveSegmentsCheckpoint()
+
+	close(errs)
+	if err := <-errs; err != nil {
+		return false, err
+	}
+
+	for _, segment := range w.download.Segments {
+		if !segment.Done() {
+			return false, nil // Paused
+		}
+	}
+	return true, nil
+}
+
+// downloadSegment downloads the byte range of the segment at index i from its
+// current offset, writing at the matching file offset.
+func (w *DownloadWorker) downloadSegment(i int, stop *atomic.Bool) error {
+	segment := w.snapshot().Segments[i]
+
+	reader, _, statusCode, err := w.provider.DownloadRange(w.ctx, *w.download.DownloadURL, segment.Offset(), segment.End)
+	if err != nil {
+		return fmt.Errorf("failed to start download of segment %d: %w", i, err)
+	}
+	defer reader.Close()
+	defer w.trackBody(reader)()
+
+	if statusCode != http.StatusPartialContent {
+		return errRangeNotSupported
+	}
+
+	totalSize := *w.download.FileSize
+	offset := segment.Offset()
+	body := w.limitReader(reader)
+	buffer := make([]byte, 64*1024)
+
+	for {
+		if w.IsCancelled() {
+			return errors.New("cancelled")
+		}
+		if w.IsPaused() || stop.Load() {
+			return nil
+		}
+
+		n, readErr := body.Read(buffer)
+
+		if n > 0 {
+			// Never write past the end of the segment
+			n = int(min(int64(n), segment.End-offset+1))
+			if _, err := w.file.WriteAt(buffer[:n], offset); err != nil {
+				return fmt.Errorf("failed to write segment %d: %w", i, err)
+			}
+			offset += int64(n)
+
+			w.UpdateDownload(func(d *model.Download) {
+				w.segmentHashes[i].Write(buffer[:n])
+				d.Segments[i].Downloaded += int64(n)
+				d.DownloadedBytes += int64(n)
+				d.Progress = float64(d.DownloadedBytes) / float64(totalSize) * 100
+			})
+
+			if offset > segment.End {
+				return nil // Segment complete
+			}
+		}
+
+		// The body was closed by a pause or a cancel request
+		if readErr != nil && w.IsCancelled() {
+			return errors.New("cancelled")
+		}
+		if readErr != nil && w.IsPaused() {
+			return nil
+		}
+
+		if readErr == io.EOF {
+			return fmt.Errorf("segment %d ended early: %w", i, io.ErrUnexpectedEOF)
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// disableSegments switches the download back to a single connection, from zero.
+func (w *DownloadWorker) disableSegments() error {
+	log.Warnf("Range requests not supported for %s, falling back to a single connection", w.download.ID)
+
+	w.closeFile()
+	removeCheckpoint(w.download)
+	w.segments = 1
+	w.UpdateDownload(func(d *model.Download) {
+		d.Segments = nil
+		d.DownloadedBytes = 0
+		d.Progress = 0
+	})
+
+	return w.prepareFile()
+}
@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"dlbackend/internal/model"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSegments(t *testing.T) {
+	t.Run("too small to split", func(t *testing.T) {
+		assert.Nil(t, splitSegments(minSegmentSize, 4))
+	})
+
+	t.Run("single segment requested", func(t *testing.T) {
+		assert.Nil(t, splitSegments(100*minSegmentSize, 1))
+	})
+
+	t.Run("limited by minimum segment size", func(t *testing.T) {
+		segments := splitSegments(3*minSegmentSize, 8)
+		assert.Len(t, segments, 3)
+	})
+
+	t.Run("ranges cover the whole file", func(t *testing.T) {
+		size := 10*minSegmentSize + 7
+		segments := splitSegments(size, 4)
+		require.Len(t, segments, 4)
+
+		assert.Equal(t, int64(0), segments[0].Start)
+		for i := 1; i < len(segments); i++ {
+			assert.Equal(t, segments[i-1].End+1, segments[i].Start)
+		}
+		assert.Equal(t, size-1, segments[3].End)
+	})
+}
+
+func TestDownloadWorker_DownloadSegments(t *testing.T) {
+	setupTestConfig(t)
+
+	downloadURL := "https://download.1fichier.com/test"
+
+	t.Run("segments are written at their offset", func(t *testing.T) {
+		content := "0123456789abcdefghij"
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withSize(int64(len(content))), withSegments([]model.DownloadSegment{
+			{Start: 0, End: 9},
+			{Start: 10, End: 19},
+		}))
+		mockClient := worker.mockClient
+
+		mockClient.On("DownloadRange", downloadURL, int64(0), int64(9)).Return(
+			&MockReadCloser{reader: strings.NewReader(content[:10])}, int64(10), http.StatusPartialContent, nil,
+		)
+		mockClient.On("DownloadRange", downloadURL, int64(10), int64(19)).Return(
+			&MockReadCloser{reader: strings.NewReader(content[10:])}, int64(10), http.StatusPartialContent, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		completed, err := worker.downloadSegments()
+		worker.closeFile()
+
+		require.NoError(t, err)
+		assert.True(t, completed)
+		assert.Equal(t, int64(20), worker.download.DownloadedBytes)
+		assert.Equal(t, float64(100), worker.download.Progress)
+		for _, segment := range worker.download.Segments {
+			assert.True(t, segment.Done())
+		}
+
+		tempPath, _ := worker.download.TempFilePath()
+		data, err := os.ReadFile(tempPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+		os.Remove(tempPath)
+	})
+
+	t.Run("resume only fetches missing ranges", func(t *testing.T) {
+		worker := newTestWorker(t,
+			withDownloadURL(downloadURL),
+			withSize(20),
+			withSegments([]model.DownloadSegment{
+				{Start: 0, End: 9, Downloaded: 10},
+				{Start: 10, End: 19, Downloaded: 4},
+			}),
+			withDownloaded(14),
+			withContent("0123456789abcd"),
+		)
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "test-id", Segments: []segmentCheckpoint{
+			{Start: 0, Downloaded: 10, Hash: integrityHex("0123456789")},
+			{Start: 10, Downloaded: 4, Hash: integrityHex("abcd")},
+		}}))
+		mockClient := worker.mockClient
+
+		mockClient.On("DownloadRange", downloadURL, int64(14), int64(19)).Return(
+			&MockReadCloser{reader: strings.NewReader("efghij")}, int64(6), http.StatusPartialContent, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		completed, err := worker.downloadSegments()
+		worker.closeFile()
+
+		require.NoError(t, err)
+		assert.True(t, completed)
+		mockClient.AssertNumberOfCalls(t, "DownloadRange", 1)
+
+		data, _ := os.ReadFile(worker.tempPath)
+		assert.Equal(t, "0123456789abcdefghij", string(data))
+	})
+
+	t.Run("segments record their offsets in the checkpoint", func(t *testing.T) {
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withSize(20), withContent(""), withSegments([]model.DownloadSegment{
+			{Start: 0, End: 9},
+			{Start: 10, End: 19},
+		}))
+		worker.mockClient.On("DownloadRange", downloadURL, int64(0), int64(9)).Return(
+			&MockReadCloser{reader: strings.NewReader("0123456789")}, int64(10), http.StatusPartialContent, nil,
+		)
+		// The second segment fails once the first one is complete, which the error would stop
+		firstDone := &waitReader{ready: func() bool { return worker.snapshot().Segments[0].Done() }}
+		worker.mockClient.On("DownloadRange", downloadURL, int64(10), int64(19)).Return(
+			&MockReadCloser{reader: io.MultiReader(strings.NewReader("abc"), firstDone)}, int64(10), http.StatusPartialContent, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		_, err := worker.downloadSegments()
+		worker.closeFile()
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+		cp := readCheckpoint(worker.download)
+		require.NotNil(t, cp)
+		assert.Equal(t, []segmentCheckpoint{
+			{Start: 0, Downloaded: 10, Hash: integrityHex("0123456789")},
+			{Start: 10, Downloaded: 3, Hash: integrityHex("abc")},
+		}, cp.Segments)
+	})
+
+	t.Run("resume without checkpoint restarts from zero", func(t *testing.T) {
+		// Crash before the first checkpoint: the preallocated file is full of holes
+		worker := newTestWorker(t,
+			withSize(20),
+			withSegments([]model.DownloadSegment{
+				{Start: 0, End: 9, Downloaded: 10},
+				{Start: 10, End: 19, Downloaded: 4},
+			}),
+			withDownloaded(14),
+			withContent(string(make([]byte, 20))),
+		)
+
+		require.NoError(t, worker.prepareFile())
+		worker.closeFile()
+
+		assert.Zero(t, worker.download.DownloadedBytes)
+		for _, segment := range worker.download.Segments {
+			assert.Zero(t, segment.Downloaded)
+		}
+	})
+
+	t.Run("resume restarts the segments not matching the checkpoint", func(t *testing.T) {
+		worker := newTestWorker(t,
+			withSize(20),
+			withSegments([]model.DownloadSegment{
+				{Start: 0, End: 9, Downloaded: 10},
+				{Start: 10, End: 19, Downloaded: 8},
+			}),
+			withDownloaded(18),
+			withContent("0123456789abcXefgh"),
+		)
+		require.NoError(t, writeCheckpoint(worker.download, checkpoint{DownloadID: "test-id", Segments: []segmentCheckpoint{
+			{Start: 0, Downloaded: 8, Hash: integrityHex("01234567")},
+			{Start: 10, Downloaded: 4, Hash: integrityHex("abcd")},
+		}}))
+
+		require.NoError(t, worker.prepareFile())
+		worker.closeFile()
+
+		// The database is ahead of the checkpoint: only the synced bytes are kept
+		assert.Equal(t, int64(8), worker.download.Segments[0].Downloaded)
+		assert.Zero(t, worker.download.Segments[1].Downloaded)
+		assert.Equal(t, int64(8), worker.download.DownloadedBytes)
+	})
+
+	t.Run("range not supported", func(t *testing.T) {
+		worker := newTestWorker(t, withDownloadURL(downloadURL), withSize(20), withSegments([]model.DownloadSegment{
+			{Start: 0, End: 9},
+			{Start: 10, End: 19},
+		}))
+		mockClient := worker.mockClient
+
+		mockClient.On("DownloadRange", downloadURL, mock.Anything, mock.Anything).Return(
+			&MockReadCloser{reader: strings.NewReader("")}, int64(20), http.StatusOK, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		completed, err := worker.downloadSegments()
+		assert.ErrorIs(t, err, errRangeNotSupported)
+		assert.False(t, completed)
+
+		require.NoError(t, worker.disableSegments())
+		assert.Nil(t, worker.download.Segments)
+		assert.Equal(t, int64(0), worker.download.DownloadedBytes)
+		worker.closeFile()
+	})
+}
+
+// waitReader ends a body once ready reports true.
+type waitReader struct {
+	ready func() bool
+}
+
+func (r *waitReader) Read(p []byte) (int, error) {
+	for !r.ready() {
+		time.Sleep(time.Millisecond)
+	}
+	return 0, io.EOF
+}
@@ -2,19 +2,24 @@ package worker
 
 import (
 	"context"
-	"dlbackend/internal/config"
 	"dlbackend/internal/model"
 	"dlbackend/internal/repository"
+	"dlbackend/internal/utils"
 	"dlbackend/pkg/client"
+	"dlbackend/pkg/ratelimit"
 	"dlbackend/pkg/sse"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v3/log"
@@ -24,43 +29,225 @@ import (
 // DOWNLOAD MANAGER
 // ============================================================================
 
+// scheduleInterval is the period at which the queue is re-evaluated even
+// when nothing explicitly woke the scheduler up.
+const scheduleInterval = 5 * time.Second
+
 type DownloadManager struct {
 	workers      sync.Map
 	repo         repository.DownloadRepository
 	settingsRepo repository.SettingsRepository
+	providers    client.Providers
 	sseManager   sse.Manager
 	ctx          context.Context
+	wake         chan struct{}      // buffered (1): coalesces schedule requests
+	limiter      *ratelimit.Limiter // Bandwidth shared by all workers (Settings.SpeedLimit)
+	freeSpace    func(path string) (int64, error)
+	progress     *progressWriter // Batched DB writes of the workers
+
+	// Download schedule (Settings.Schedule)
+	schedulePaused map[string]struct{} // Workers paused by the schedule
+	scheduleState  model.ScheduleEvent
+	scheduleMu     sync.Mutex // For schedulePaused and scheduleState
+
+	// Paused workers waiting for a free slot to resume, in request order
+	resumeQueue []string
+	resumeMu    sync.Mutex
+
+	// Serialises the starts of pending downloads with the transitions of downloads without
+	// worker (see transitionIdle): a download paused or cancelled meanwhile is not started
+	startMu sync.Mutex
+
+	// Download groups with progress to broadcast
+	dirtyGroups map[string]struct{}
+	groupsMu    sync.Mutex
 }
 
 func NewDownloadManager(
 	ctx context.Context,
 	repo repository.DownloadRepository,
 	settingsRepo repository.SettingsRepository,
+	providers client.Providers,
 	sseManager sse.Manager,
 ) *DownloadManager {
 	return &DownloadManager{
-		ctx:          ctx,
-		repo:         repo,
-		settingsRepo: settingsRepo,
-		sseManager:   sseManager,
+		ctx:            ctx,
+		repo:           repo,
+		settingsRepo:   settingsRepo,
+		providers:      providers,
+		sseManager:     sseManager,
+		wake:           make(chan struct{}, 1),
+		limiter:        ratelimit.NewLimiter(0),
+		freeSpace:      diskFreeSpace,
+		progress:       newProgressWriter(repo),
+		schedulePaused: make(map[string]struct{}),
+		dirtyGroups:    make(map[string]struct{}),
 	}
 }
 
+// Run executes the queue scheduler until the manager context is done.
+// Pending downloads are persisted in the DB, so the queue survives restarts.
+func (m *DownloadManager) Run() {
+	go m.runGroupProgress()
+	go m.runProgressWriter()
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		m.schedule()
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Schedule asks the scheduler to re-evaluate the queue (thread-safe, non-blocking).
+func (m *DownloadManager) Schedule() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// schedule promotes pending downloads to running workers while fewer than
+// Settings.MaxConcurrentDownloads workers are active, outside of paused schedule windows.
+func (m *DownloadManager) schedule() {
+	settings, err := m.settingsRepo.Get()
+	if err != nil {
+		log.Errorf("Scheduler failed to get settings: %v", err)
+		return
+	}
+
+	// Apply the global bandwidth limit and the download schedule to running transfers
+	schedule := scheduleAt(settings, time.Now())
+	m.applySchedule(schedule)
+
+	// Pause running transfers when the disk is almost full
+	available, measured := m.monitorDiskSpace(settings.DiskReserve)
+
+	if schedule.Paused {
+		return // No download starts during a paused window
+	}
+
+	slots := max(settings.MaxConcurrentDownloads, 1) - m.activeCount()
+	if slots <= 0 {
+		return
+	}
+
+	// Below the reserve, even a download of unknown size could fill the disk
+	if measured && available < 0 {
+		log.Debugf("No download started: %d bytes missing to keep the disk reserve", -available)
+		return
+	}
+
+	// Paused workers waiting for a slot go first: they already hold data
+	slots -= m.resumeQueued(slots)
+	if slots <= 0 {
+		return
+	}
+
+	pending, err := m.repo.GetPending()
+	if err != nil {
+		log.Errorf("Scheduler failed to get pending downloads: %v", err)
+		return
+	}
+
+	for i := range pending {
+		if slots == 0 {
+			return
+		}
+		if _, running := m.workers.Load(pending[i].ID); running {
+			continue
+		}
+		// Downloads that don't fit wait in the queue, smaller ones may start meanwhile
+		if measured {
+			need := plannedBytes(&pending[i], settings.DiskReserve)
+			if need > available {
+				log.Debugf("Download %s waiting for disk space: %d bytes needed, %d available", pending[i].ID, need, available)
+				continue
+			}
+			available -= need
+		}
+		started, err := m.startPending(pending[i].ID)
+		if err != nil {
+			log.Warnf("Scheduler failed to start download %s: %v", pending[i].ID, err)
+			return
+		}
+		if started {
+			slots--
+		}
+	}
+}
+
+// startPending starts a download of the queue, unless its status changed since the queue
+// was read, e.g. paused or cancelled by the user. The download is read again under startMu.
+func (m *DownloadManager) startPending(downloadID string) (started bool, err error) {
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+
+	download, err := m.repo.GetByID(downloadID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get download: %w", err)
+	}
+	if _, running := m.workers.Load(downloadID); running || download.Status != model.StatusPending {
+		return false, nil
+	}
+
+	download.ErrorMessage = nil
+	if err := m.Start(download); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// activeCount returns the number of workers holding a queue slot.
+// Paused workers release their slot so the next pending download can start.
+func (m *DownloadManager) activeCount() int {
+	count := 0
+	m.workers.Range(func(_, value any) bool {
+		if !value.(*DownloadWorker).IsPaused() {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// Start spawns a worker for the download immediately, bypassing the queue.
+// Use Schedule to respect the concurrency limit.
 func (m *DownloadManager) Start(download *model.Download) error {
 	settings, err := m.settingsRepo.Get()
 	if err != nil {
 		return fmt.Errorf("failed to get settings: %w", err)
 	}
-	if settings.APIKey1fichier == "" {
+	provider, err := m.providers.ByName(download.Provider)
+	if err != nil {
+		return err
+	}
+	if provider.Name() == client.ProviderOneFichier && settings.APIKey1fichier == "" {
 		return fmt.Errorf("API key not configured")
 	}
 
-	client := client.NewOneFichierClient(config.Cfg.ApiUrl1fichier, settings.APIKey1fichier)
-	worker := NewDownloadWorker(m.ctx, download, m.repo, client, m.sseManager)
+	worker := NewDownloadWorker(m.ctx, download, m.repo, provider, m.sseManager)
+	worker.segments = max(settings.SegmentsPerDownload, 1)
+	worker.conflictPolicy = settings.DefaultConflictPolicy()
+	worker.diskReserve = settings.DiskReserve
+	worker.freeSpace = m.freeSpace
+	worker.sameFile = m.completedSameFile
+	worker.reservedPaths = m.reservedPaths
+	worker.globalLimiter = m.limiter
+	worker.onProgress = m.markGroupDirty
+	worker.progress = m.progress
 
 	m.workers.Store(download.ID, worker)
 
 	go func() {
+		defer m.Schedule()
 		defer m.workers.Delete(download.ID)
 		worker.Run()
 	}()
@@ -69,41 +256,277 @@ func (m *DownloadManager) Start(download *model.Download) error {
 }
 
 func (m *DownloadManager) Pause(downloadID string) error {
-	value, ok := m.workers.Load(downloadID)
-	if !ok {
-		return errors.New("download not found")
+	worker, err := m.workerOrTransition(downloadID, model.StatusPaused, model.StatusPending)
+	if worker == nil {
+		return err
 	}
 
-	// Safe: workers only stores *DownloadWorker values (see Start).
-	worker := value.(*DownloadWorker)
-	worker.Pause()
+	m.keepPaused(downloadID)
+	if m.dequeueResume(downloadID) {
+		// Still paused, waiting for a slot: back to a plain pause
+		worker.UpdateDownload(func(d *model.Download) {
+			d.Status = model.StatusPaused
+		})
+		worker.notifyProgress()
+		return nil
+	}
+	if err := worker.Pause(); err != nil {
+		return err
+	}
+	m.Schedule()
 	return nil
 }
 
 func (m *DownloadManager) Resume(downloadID string) error {
+	worker, err := m.workerOrTransition(downloadID, model.StatusPending, model.StatusPaused)
+	if worker == nil {
+		if err != nil {
+			return err
+		}
+		m.Schedule()
+		return nil
+	}
+
+	if !worker.IsPaused() {
+		return worker.Resume()
+	}
+
+	// The slot of the paused worker may have been given to another download:
+	// without a free slot, it waits as pending for the scheduler to resume it
+	settings, err := m.settingsRepo.Get()
+	if err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+	if m.activeCount() >= max(settings.MaxConcurrentDownloads, 1) {
+		m.queueResume(worker)
+		return nil
+	}
+	return worker.Resume()
+}
+
+// queueResume makes a paused worker wait for a free slot to resume, shown as pending.
+func (m *DownloadManager) queueResume(worker *DownloadWorker) {
+	m.resumeMu.Lock()
+	defer m.resumeMu.Unlock()
+
+	if slices.Contains(m.resumeQueue, worker.download.ID) {
+		return
+	}
+	m.resumeQueue = append(m.resumeQueue, worker.download.ID)
+
+	worker.UpdateDownload(func(d *model.Download) {
+		d.Status = model.StatusPending
+	})
+	worker.notifyProgress()
+	log.Infof("Download %s waiting for a free slot to resume", worker.download.ID)
+}
+
+// dequeueResume removes the download from the resume queue, reporting whether it was queued.
+func (m *DownloadManager) dequeueResume(downloadID string) bool {
+	m.resumeMu.Lock()
+	defer m.resumeMu.Unlock()
+
+	i := slices.Index(m.resumeQueue, downloadID)
+	if i < 0 {
+		return false
+	}
+	m.resumeQueue = slices.Delete(m.resumeQueue, i, i+1)
+	return true
+}
+
+// resumeQueued resumes up to slots workers of the resume queue, in request order,
+// and returns the number of workers resumed. Workers no longer paused are dropped.
+func (m *DownloadManager) resumeQueued(slots int) int {
+	m.resumeMu.Lock()
+	defer m.resumeMu.Unlock()
+
+	resumed := 0
+	for len(m.resumeQueue) > 0 && resumed < slots {
+		id := m.resumeQueue[0]
+		m.resumeQueue = m.resumeQueue[1:]
+
+		value, ok := m.workers.Load(id)
+		if !ok || !value.(*DownloadWorker).IsPaused() {
+			continue
+		}
+		if value.(*DownloadWorker).Resume() == nil {
+			resumed++
+		}
+	}
+	return resumed
+}
+
+func (m *DownloadManager) Cancel(downloadID string) error {
+	worker, err := m.workerOrTransition(downloadID, model.StatusCancelled, model.StatusPending, model.StatusPaused)
+	if worker == nil {
+		return err
+	}
+	return worker.Cancel()
+}
+
+// SetSpeedLimit changes the bandwidth limit of a download (nil or 0 = unlimited).
+// A running transfer picks up the new limit immediately.
+func (m *DownloadManager) SetSpeedLimit(downloadID string, speedLimit *int64) error {
+	if speedLimit != nil && *speedLimit <= 0 {
+		speedLimit = nil
+	}
+
 	value, ok := m.workers.Load(downloadID)
-	if !ok {
-		return errors.New("download not found")
+	if ok {
+		// Safe: workers only stores *DownloadWorker values (see Start).
+		value.(*DownloadWorker).SetSpeedLimit(speedLimit)
+		return nil
 	}
 
-	// Safe: workers only stores *DownloadWorker values (see Start).
-	worker := value.(*DownloadWorker)
-	worker.Resume()
+	download, err := m.repo.GetByID(downloadID)
+	if err != nil {
+		return ErrDownloadNotFound
+	}
+	download.SpeedLimit = speedLimit
+	if err := m.repo.Update(download); err != nil {
+		return fmt.Errorf("failed to update download: %w", err)
+	}
 	return nil
 }
 
-func (m *DownloadManager) Cancel(downloadID string) error {
+// SetPriority changes the queue priority of a download. It only orders the pending
+// queue: a running download keeps its slot.
+func (m *DownloadManager) SetPriority(downloadID string, priority int) error {
 	value, ok := m.workers.Load(downloadID)
-	if !ok {
-		return errors.New("download not found")
+	if ok {
+		// Safe: workers only stores *DownloadWorker values (see Start).
+		value.(*DownloadWorker).SetPriority(priority)
+		return nil
 	}
 
-	// Safe: workers only stores *DownloadWorker values (see Start).
-	worker := value.(*DownloadWorker)
-	worker.Cancel()
+	download, err := m.repo.GetByID(downloadID)
+	if err != nil {
+		return ErrDownloadNotFound
+	}
+	download.Priority = priority
+	if err := m.repo.Update(download); err != nil {
+		return fmt.Errorf("failed to update download: %w", err)
+	}
 	return nil
 }
 
+// Restore reconciles the downloads left active by a previous run.
+// Downloads whose temp file still matches DownloadedBytes, or their checkpoint, are
+// queued again and resume from that offset; the others are marked as paused so the user can
+// decide to restart them from zero.
+func (m *DownloadManager) Restore() error {
+	downloads, err := m.repo.GetActive()
+	if err != nil {
+		return fmt.Errorf("failed to get active downloads: %w", err)
+	}
+
+	for i := range downloads {
+		download := &downloads[i]
+		if download.Status == model.StatusPending {
+			continue // Already queued
+		}
+
+		// The download token does not survive a restart
+		download.DownloadURL = nil
+		download.DownloadURLExpiresAt = nil
+		download.Speed = nil
+		download.ETA = nil
+
+		if tempFileMatches(download) {
+			download.Status = model.StatusPending
+			log.Infof("Download %s interrupted, queued again from offset %d", download.ID, download.DownloadedBytes)
+		} else {
+			errMsg := "interrupted by a restart and temp file lost, resume restarts from zero"
+			download.Status = model.StatusPaused
+			download.ErrorMessage = &errMsg
+			download.DownloadedBytes = 0
+			download.Progress = 0
+			log.Warnf("Download %s interrupted and temp file lost, marked as paused", download.ID)
+		}
+
+		if err := m.repo.Update(download); err != nil {
+			return fmt.Errorf("failed to restore download %s: %w", download.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// workerOrTransition returns the worker of the download if it's running. Otherwise it
+// moves the download to the target status (see transitionIdle) and returns a nil worker.
+// Both happen under startMu: the scheduler can't start the download in between.
+func (m *DownloadManager) workerOrTransition(downloadID string, to model.DownloadStatus, from ...model.DownloadStatus) (*DownloadWorker, error) {
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+
+	if value, ok := m.workers.Load(downloadID); ok {
+		// Safe: workers only stores *DownloadWorker values (see Start).
+		return value.(*DownloadWorker), nil
+	}
+	return nil, m.transitionIdle(downloadID, to, from...)
+}
+
+// transitionIdle moves a download without running worker (queued, paused, or
+// restored after a restart) to the target status, if its current status is allowed.
+// Called under startMu, see workerOrTransition.
+func (m *DownloadManager) transitionIdle(downloadID string, to model.DownloadStatus, from ...model.DownloadStatus) error {
+	download, err := m.repo.GetByID(downloadID)
+	if err != nil {
+		return ErrDownloadNotFound
+	}
+	if !slices.Contains(from, download.Status) {
+		return &TransitionError{From: string(download.Status), To: string(to)}
+	}
+
+	download.Status = to
+	download.Speed = nil
+	download.ETA = nil
+	if to == model.StatusCancelled {
+		if tempPath, err := download.TempFilePath(); err == nil {
+			os.Remove(tempPath)
+		}
+		removeCheckpoint(download)
+	}
+
+	if err := m.repo.Update(download); err != nil {
+		return fmt.Errorf("failed to update download: %w", err)
+	}
+	if err := m.sseManager.SendEvent("progress", download.ProgressEvent()); err != nil {
+		log.Errorf("Failed to send SSE for download %s: %v", download.ID, err)
+	}
+	m.markGroupDirty(download)
+
+	return nil
+}
+
+// tempFileMatches reports whether the temp file of the download can be used to resume it.
+func tempFileMatches(download *model.Download) bool {
+	if download.DownloadedBytes == 0 {
+		return true
+	}
+	tempPath, err := download.TempFilePath()
+	if err != nil {
+		return false
+	}
+	stat, err := os.Stat(tempPath)
+	if err != nil {
+		return false
+	}
+
+	// Data is written at its offset, in a possibly preallocated file: the file must
+	// reach the furthest written byte, or the checkpoint of a single connection
+	end := download.DownloadedBytes
+	if cp := readCheckpoint(download); cp != nil && len(download.Segments) == 0 {
+		end = cp.Offset
+	}
+	for _, segment := range download.Segments {
+		if segment.Downloaded > 0 {
+			end = max(end, segment.Offset())
+		}
+	}
+	return stat.Size() >= end
+}
+
 // ============================================================================
 // DOWNLOAD WORKER - Architecture Event-Driven
 // ============================================================================
@@ -111,77 +534,299 @@ func (m *DownloadManager) Cancel(downloadID string) error {
 type DownloadWorker struct {
 	download   *model.Download
 	repo       repository.DownloadRepository
-	client     client.OneFichierClient
+	provider   client.Provider
 	sseManager sse.Manager
 
-	// State control via atomics (no mutex needed)
-	state atomic.Int32 // 0=running, 1=paused, 2=cancelled
+	// Retry of transient failures
+	retryPolicy RetryPolicy
+
+	// Number of parallel connections (byte ranges) for the download
+	segments int
+
+	// Applied when the final file already exists, unless the download has its own policy
+	conflictPolicy model.ConflictPolicy
+
+	// Duplicates found once the file info is known (see checkConflicts), nil to skip the checks
+	sameFile      func(download *model.Download) (*model.Download, error)
+	reservedPaths func(downloadID string) (map[string]bool, error)
+
+	// Free space kept on the disk (Settings.DiskReserve)
+	diskReserve int64
+	freeSpace   func(path string) (int64, error)
+	// Set once the blocks of the whole temp file are reserved: the download needs no more space
+	preallocated atomic.Bool
+
+	// Bandwidth limits: per download (Download.SpeedLimit) and shared by all workers
+	limiter       *ratelimit.Limiter
+	globalLimiter *ratelimit.Limiter
+
+	// Called after each progress notification (e.g. group progress), may be nil
+	onProgress func(download *model.Download)
+
+	// Batches the DB writes of progress ticks, nil to write every update immediately
+	progress *progressWriter
+
+	// State machine: transitions are made under stateMu and broadcast on stateCond,
+	// the current state is also readable without lock
+	state     atomic.Int32 // WorkerState
+	stateMu   sync.Mutex
+	stateCond *sync.Cond
+
+	// Response bodies being read, closed on pause and cancel to stop reading immediately
+	bodies   map[io.Closer]struct{}
+	bodiesMu sync.Mutex
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	// File writing
-	file *os.File
-	mu   sync.Mutex // Only for file operations
+	file          *os.File
+	hasher        hash.Hash   // Incremental checksum of the temp file, nil if not verifiable
+	integrity     hash.Hash   // Rolling hash saved in checkpoints, nil for segments
+	segmentHashes []hash.Hash // Rolling hash of each segment saved in checkpoints, nil for a single connection
+	mu            sync.Mutex  // Only for file operations
 }
 
+// WorkerState is the state of a download worker.
+type WorkerState int32
+
 const (
-	StateRunning = iota
+	StateRunning WorkerState = iota
 	StatePaused
 	StateCancelled
+	StateDone // Run returned: completed, failed or corrupted
+)
+
+func (s WorkerState) String() string {
+	switch s {
+	case StateRunning:
+		return "RUNNING"
+	case StatePaused:
+		return "PAUSED"
+	case StateCancelled:
+		return "CANCELLED"
+	case StateDone:
+		return "DONE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// workerTransitions lists the allowed state transitions. Cancelled and done are final.
+var workerTransitions = map[WorkerState][]WorkerState{
+	StateRunning: {StatePaused, StateCancelled, StateDone},
+	StatePaused:  {StateRunning, StateCancelled, StateDone},
+}
+
+// ErrDownloadNotFound is returned when the download does not exist.
+var ErrDownloadNotFound = errors.New("download not found")
+
+// ErrInvalidTransition is returned when a pause, resume or cancel request does
+// not apply to the current state, e.g. resuming a completed download.
+var ErrInvalidTransition = errors.New("invalid state transition")
+
+// TransitionError is the rejected transition of a download, it matches ErrInvalidTransition.
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("invalid state transition from %s to %s", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+const (
+	// downloadURLRenewMargin renews the download URL slightly before its expiry
+	downloadURLRenewMargin = 10 * time.Second
+	// maxDownloadURLRenewals limits consecutive renewals without progress
+	maxDownloadURLRenewals = 3
 )
 
 func NewDownloadWorker(
 	ctx context.Context,
 	download *model.Download,
 	repo repository.DownloadRepository,
-	client client.OneFichierClient,
+	provider client.Provider,
 	sseManager sse.Manager,
 ) *DownloadWorker {
 	workerCtx, cancel := context.WithCancel(ctx)
 
 	w := &DownloadWorker{
-		download:   download,
-		repo:       repo,
-		client:     client,
-		sseManager: sseManager,
-		ctx:        workerCtx,
-		cancel:     cancel,
+		download:       download,
+		repo:           repo,
+		provider:       provider,
+		sseManager:     sseManager,
+		retryPolicy:    DefaultRetryPolicy(),
+		segments:       1,
+		conflictPolicy: model.ConflictFail,
+		freeSpace:      diskFreeSpace,
+		limiter:        ratelimit.NewLimiter(speedLimitRate(download.SpeedLimit)),
+		bodies:         make(map[io.Closer]struct{}),
+		ctx:            workerCtx,
+		cancel:         cancel,
 	}
 
-	w.state.Store(StateRunning)
+	w.stateCond = sync.NewCond(&w.stateMu)
+	w.state.Store(int32(StateRunning))
+
+	// Wake up a paused worker when the context is done (shutdown)
+	context.AfterFunc(workerCtx, func() {
+		w.stateMu.Lock()
+		defer w.stateMu.Unlock()
+		w.stateCond.Broadcast()
+	})
+
 	return w
 }
 
-// Pause requests a pause (thread-safe, non-blocking).
-func (w *DownloadWorker) Pause() {
-	w.state.Store(StatePaused)
+// Pause requests a pause (thread-safe, non-blocking, idempotent).
+// The response body being read is closed right away; the transfer resumes
+// from the current offset with a new Range request.
+func (w *DownloadWorker) Pause() error {
+	changed, err := w.transition(StatePaused)
+	if err != nil || !changed {
+		return err
+	}
+
 	log.Infof("Pause requested for download %s", w.download.ID)
+	w.closeBodies()
+	return nil
 }
 
-// Resume cancels the pause (thread-safe, non-blocking).
-func (w *DownloadWorker) Resume() {
-	if w.state.CompareAndSwap(StatePaused, StateRunning) {
-		log.Infof("Resume requested for download %s", w.download.ID)
+// Resume cancels the pause (thread-safe, non-blocking, idempotent).
+func (w *DownloadWorker) Resume() error {
+	changed, err := w.transition(StateRunning)
+	if err != nil || !changed {
+		return err
 	}
+
+	log.Infof("Resume requested for download %s", w.download.ID)
+	return nil
 }
 
-// Cancel stops the download (idempotent).
-func (w *DownloadWorker) Cancel() {
-	if w.state.Swap(StateCancelled) != StateCancelled {
-		log.Infof("Cancel requested for download %s", w.download.ID)
-		w.cancel()
+// Cancel stops the download (thread-safe, non-blocking, idempotent).
+func (w *DownloadWorker) Cancel() error {
+	changed, err := w.transition(StateCancelled)
+	if err != nil || !changed {
+		return err
 	}
+
+	log.Infof("Cancel requested for download %s", w.download.ID)
+	w.cancel()
+	w.closeBodies()
+	return nil
+}
+
+// State returns the current state of the worker.
+func (w *DownloadWorker) State() WorkerState {
+	return WorkerState(w.state.Load())
 }
 
 // IsPaused reports whether the worker is currently paused.
 func (w *DownloadWorker) IsPaused() bool {
-	return w.state.Load() == StatePaused
+	return w.State() == StatePaused
 }
 
 // IsCancelled reports whether the worker has been cancelled.
 func (w *DownloadWorker) IsCancelled() bool {
-	return w.state.Load() == StateCancelled
+	return w.State() == StateCancelled
+}
+
+// transition moves the worker to the given state if the transition is allowed,
+// and wakes up the goroutines waiting for a state change. Moving to the current
+// state is a no-op and reports changed=false.
+func (w *DownloadWorker) transition(to WorkerState) (changed bool, err error) {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+
+	from := w.State()
+	if from == to {
+		return false, nil
+	}
+	if !slices.Contains(workerTransitions[from], to) {
+		return false, &TransitionError{From: from.String(), To: to.String()}
+	}
+
+	w.state.Store(int32(to))
+	w.stateCond.Broadcast()
+	return true, nil
+}
+
+// waitWhilePaused blocks until the worker leaves the paused state.
+// It returns false if the worker was cancelled or its context is done meanwhile.
+func (w *DownloadWorker) waitWhilePaused() bool {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+
+	for w.State() == StatePaused && w.ctx.Err() == nil {
+		w.stateCond.Wait()
+	}
+	return w.State() != StateCancelled && w.ctx.Err() == nil
+}
+
+// trackBody registers a response body to close on pause or cancel.
+// The returned function unregisters it.
+func (w *DownloadWorker) trackBody(body io.Closer) (untrack func()) {
+	w.bodiesMu.Lock()
+	w.bodies[body] = struct{}{}
+	w.bodiesMu.Unlock()
+
+	// Paused or cancelled while the request was being sent
+	if w.State() != StateRunning {
+		body.Close()
+	}
+
+	return func() {
+		w.bodiesMu.Lock()
+		defer w.bodiesMu.Unlock()
+		delete(w.bodies, body)
+	}
+}
+
+// closeBodies closes the response bodies being read, unblocking their readers.
+func (w *DownloadWorker) closeBodies() {
+	w.bodiesMu.Lock()
+	defer w.bodiesMu.Unlock()
+
+	for body := range w.bodies {
+		body.Close()
+	}
+}
+
+// SetSpeedLimit changes the bandwidth limit of the download (thread-safe, nil = unlimited).
+func (w *DownloadWorker) SetSpeedLimit(speedLimit *int64) {
+	w.UpdateDownload(func(d *model.Download) {
+		d.SpeedLimit = speedLimit
+	})
+	w.limiter.SetRate(speedLimitRate(speedLimit))
+	w.notifyProgress()
+
+	log.Infof("Speed limit of download %s set to %d B/s", w.download.ID, speedLimitRate(speedLimit))
+}
+
+// SetPriority changes the queue priority of the download (thread-safe).
+func (w *DownloadWorker) SetPriority(priority int) {
+	w.UpdateDownload(func(d *model.Download) {
+		d.Priority = priority
+	})
+	w.notifyProgress()
+}
+
+// speedLimitRate converts an optional speed limit to a limiter rate (0 = unlimited).
+func speedLimitRate(speedLimit *int64) int64 {
+	if speedLimit == nil {
+		return 0
+	}
+	return *speedLimit
+}
+
+// limitReader applies the bandwidth limits to a response body.
+func (w *DownloadWorker) limitReader(reader io.Reader) io.Reader {
+	return ratelimit.NewReader(w.ctx, reader, w.globalLimiter, w.limiter)
 }
 
 // UpdateDownload applies fn to the download struct (thread-safe).
@@ -191,60 +836,104 @@ func (w *DownloadWorker) UpdateDownload(fn func(*model.Download)) {
 	fn(w.download)
 }
 
+// snapshot returns a copy of the download (thread-safe), to be used outside the worker goroutines.
+func (w *DownloadWorker) snapshot() *model.Download {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.download.Clone()
+}
+
 // notifyProgress persists the current download state to the DB and broadcasts an SSE progress event.
+// Used on state transitions: progress ticks go through reportProgress.
 func (w *DownloadWorker) notifyProgress() {
-	if err := w.repo.Update(w.download); err != nil {
+	w.publishProgress(true)
+}
+
+// reportProgress broadcasts an SSE progress event; the DB write is buffered and
+// coalesced with the next ones.
+func (w *DownloadWorker) reportProgress() {
+	w.publishProgress(false)
+}
+
+// publishProgress persists the current download state, immediately or buffered,
+// and broadcasts an SSE progress event.
+func (w *DownloadWorker) publishProgress(immediate bool) {
+	download := w.snapshot()
+	var err error
+	switch {
+	case w.progress == nil:
+		err = w.repo.Update(download)
+	case immediate:
+		err = w.progress.write(download)
+	default:
+		w.progress.queue(download)
+	}
+	if err != nil {
 		log.Errorf("Failed to update DB for download %s: %v", w.download.ID, err)
 	}
 
-	event := model.DownloadProgressEvent{
-		DownloadID:      w.download.ID,
-		FileName:        w.download.FileName,
-		CustomFileDir:   w.download.CustomFileDir,
-		CustomFileName:  w.download.CustomFileName,
-		Status:          string(w.download.Status),
-		Progress:        w.download.Progress,
-		DownloadedBytes: w.download.DownloadedBytes,
-		FileSize:        w.download.FileSize,
-		Speed:           w.download.Speed,
+	if err := w.sseManager.SendEvent("progress", download.ProgressEvent()); err != nil {
+		log.Errorf("Failed to send SSE for download %s: %v", w.download.ID, err)
 	}
 
-	if err := w.sseManager.SendEvent("progress", event); err != nil {
-		log.Errorf("Failed to send SSE for download %s: %v", w.download.ID, err)
+	if w.onProgress != nil {
+		w.onProgress(download)
 	}
 }
 
 // Run executes the full download workflow sequentially.
 func (w *DownloadWorker) Run() error {
+	defer w.transition(StateDone)
 	defer w.cleanup()
 
-	// Sequential steps
-	if err := w.stepGetFileInfo(); err != nil {
-		return w.fail(err)
-	}
-
-	if err := w.stepGetDownloadToken(); err != nil {
-		return w.fail(err)
+	// Sequential steps, each one retried on transient failures
+	steps := []func() error{
+		w.stepGetFileInfo,
+		w.stepGetDownloadToken,
+		w.stepDownload,
 	}
-
-	if err := w.stepDownload(); err != nil {
-		if w.IsCancelled() {
-			return w.cancelCleanup()
+	for _, step := range steps {
+		if err := w.withRetry(step); err != nil {
+			if w.IsCancelled() {
+				return w.cancelCleanup()
+			}
+			if errors.Is(err, ErrInsufficientDiskSpace) {
+				return w.requeue(err)
+			}
+			if errors.Is(err, errConflictSkipped) {
+				return w.skip()
+			}
+			return w.fail(err)
 		}
-		return w.fail(err)
 	}
 
 	return w.complete()
 }
 
-// stepGetFileInfo fetches file metadata from the 1fichier API.
+// password returns the decrypted password of a protected file, "" if there is none.
+func (w *DownloadWorker) password() (string, error) {
+	if w.download.Password == nil {
+		return "", nil
+	}
+	password, err := utils.DecryptSecret(*w.download.Password)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt password: %w", err)
+	}
+	return password, nil
+}
+
+// stepGetFileInfo fetches file metadata from the provider.
 func (w *DownloadWorker) stepGetFileInfo() error {
 	w.UpdateDownload(func(d *model.Download) {
 		d.Status = model.StatusRequestingInfos
 	})
 	w.notifyProgress()
 
-	info, err := w.client.GetFileInfo(w.download.FileURL)
+	password, err := w.password()
+	if err != nil {
+		return err
+	}
+	info, err := w.provider.GetFileInfo(w.ctx, w.download.FileURL, password)
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
@@ -259,33 +948,71 @@ func (w *DownloadWorker) stepGetFileInfo() error {
 	})
 	w.notifyProgress()
 
-	return nil
+	if err := w.checkConflicts(); err != nil {
+		return err
+	}
+
+	// The size is known: make sure the file fits on the disk before downloading
+	return w.checkDiskSpace()
 }
 
-// stepGetDownloadToken fetches a time-limited download token from the 1fichier API.
-// WARNING: the token is valid for 5 minutes only. Downloads longer than 5 minutes
-// will fail mid-transfer. Token renewal on expiry is not yet implemented.
+// stepGetDownloadToken resolves the download URL with the provider. A 1fichier token
+// is valid for 5 minutes only: stepDownload renews it when it expires.
 func (w *DownloadWorker) stepGetDownloadToken() error {
 	w.UpdateDownload(func(d *model.Download) {
 		d.Status = model.StatusRequestingToken
 	})
 	w.notifyProgress()
 
-	token, err := w.client.GetDownloadToken(w.download.FileURL)
+	if err := w.requestDownloadToken(); err != nil {
+		return err
+	}
+	w.notifyProgress()
+
+	return nil
+}
+
+// requestDownloadToken fetches a new download URL and records its expiry, if any.
+func (w *DownloadWorker) requestDownloadToken() error {
+	password, err := w.password()
+	if err != nil {
+		return err
+	}
+	link, err := w.provider.ResolveDownloadURL(w.ctx, w.download.FileURL, password)
 	if err != nil {
 		return fmt.Errorf("failed to get download token: %w", err)
 	}
 
 	w.UpdateDownload(func(d *model.Download) {
-		d.DownloadURL = &token.URL
-		expiresAt := time.Now().Add(5 * time.Minute)
-		d.DownloadURLExpiresAt = &expiresAt
+		d.DownloadURL = &link.URL
+		d.DownloadURLExpiresAt = nil
+		if link.TTL > 0 {
+			expiresAt := time.Now().Add(link.TTL)
+			d.DownloadURLExpiresAt = &expiresAt
+		}
 	})
+
+	return nil
+}
+
+// renewDownloadToken replaces an expired download URL, keeping the current offset.
+func (w *DownloadWorker) renewDownloadToken() error {
+	log.Infof("Download URL expired for %s, requesting a new token at offset %d", w.download.ID, w.download.DownloadedBytes)
+
+	if err := w.requestDownloadToken(); err != nil {
+		return err
+	}
 	w.notifyProgress()
 
 	return nil
 }
 
+// isDownloadURLExpired reports whether the download URL is expired, or about to.
+func (w *DownloadWorker) isDownloadURLExpired() bool {
+	expiresAt := w.download.DownloadURLExpiresAt
+	return expiresAt != nil && time.Now().Add(downloadURLRenewMargin).After(*expiresAt)
+}
+
 // stepDownload performs the actual file download with pause/resume support.
 func (w *DownloadWorker) stepDownload() error {
 	now := time.Now()
@@ -307,7 +1034,13 @@ func (w *DownloadWorker) stepDownload() error {
 	}
 	defer w.closeFile()
 
+	// Nothing left to download (e.g. interrupted while verifying)
+	if w.download.FileSize != nil && *w.download.FileSize > 0 && w.download.DownloadedBytes >= *w.download.FileSize {
+		return nil
+	}
+
 	// Download loop: retries automatically after each pause
+	renewals := 0
 	for {
 		if w.IsCancelled() {
 			return errors.New("cancelled")
@@ -322,14 +1055,8 @@ func (w *DownloadWorker) stepDownload() error {
 
 			log.Debugf("Download %s paused, waiting for resume...", w.download.ID)
 
-			// Active wait with periodic state check.
-			// NOTE: intentional busy-wait polling every 100ms.
-			// Consider replacing with sync.Cond.Wait() if CPU usage becomes a concern.
-			for w.IsPaused() && !w.IsCancelled() {
-				time.Sleep(100 * time.Millisecond)
-			}
-
-			if w.IsCancelled() {
+			// Block until resumed or cancelled
+			if !w.waitWhilePaused() {
 				return errors.New("cancelled")
 			}
 
@@ -341,12 +1068,51 @@ func (w *DownloadWorker) stepDownload() error {
 			log.Debugf("Download %s resumed", w.download.ID)
 		}
 
-		// Download a chunk
-		completed, err := w.downloadChunk()
+		// Renew the download URL before reconnecting if it's expired (e.g. after a long pause)
+		if w.isDownloadURLExpired() {
+			if err := w.renewDownloadToken(); err != nil {
+				return err
+			}
+		}
+
+		// Download a chunk, or all remaining byte ranges in parallel
+		offset := w.download.DownloadedBytes
+		var completed bool
+		var err error
+		if len(w.download.Segments) > 0 {
+			completed, err = w.downloadSegments()
+		} else {
+			completed, err = w.downloadChunk()
+		}
+		if w.download.DownloadedBytes > offset {
+			renewals = 0
+		}
+
+		// The server does not support range requests: fall back to a single connection
+		if errors.Is(err, errRangeNotSupported) {
+			if err := w.disableSegments(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The server rejected the URL (403/410): renew it and continue from the current offset.
+		// Renewals are limited while no byte is received, to avoid looping on a dead link.
+		if errors.Is(err, client.ErrLinkExpired) && renewals < maxDownloadURLRenewals {
+			renewals++
+			if err := w.renewDownloadToken(); err != nil {
+				return err
+			}
+			continue
+		}
 		if err != nil {
 			return err
 		}
 
+		// A body shorter than the announced size: reconnect from the offset reached
+		if completed && w.download.FileSize != nil && *w.download.FileSize > 0 && w.download.DownloadedBytes < *w.download.FileSize {
+			return fmt.Errorf("%w: %d of %d bytes received", io.ErrUnexpectedEOF, w.download.DownloadedBytes, *w.download.FileSize)
+		}
 		if completed {
 			return nil
 		}
@@ -365,34 +1131,80 @@ func (w *DownloadWorker) prepareFile() error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	// Split a new download into byte ranges when multiple connections are allowed
+	if w.segments > 1 && len(w.download.Segments) == 0 && w.download.DownloadedBytes == 0 && w.download.FileSize != nil {
+		w.UpdateDownload(func(d *model.Download) {
+			d.Segments = splitSegments(*d.FileSize, w.segments)
+		})
+	}
+
+	// A single connection resumes from its checkpoint, if any
+	var cp *checkpoint
+	if len(w.download.Segments) == 0 {
+		cp = w.loadCheckpoint()
+	}
+
 	// Check if the temp file exists and matches the expected offset (resume support)
-	if w.download.DownloadedBytes > 0 {
-		stat, err := os.Stat(tempPath)
-		if err != nil || stat.Size() != w.download.DownloadedBytes {
-			log.Warnf("Temp file mismatch, restarting from zero: %s", w.download.ID)
-			w.UpdateDownload(func(d *model.Download) {
-				d.DownloadedBytes = 0
-			})
-		}
+	if !tempFileMatches(w.download) {
+		log.Warnf("Temp file mismatch, restarting from zero: %s", w.download.ID)
+		w.UpdateDownload(func(d *model.Download) {
+			d.DownloadedBytes = 0
+			for i := range d.Segments {
+				d.Segments[i].Downloaded = 0
+			}
+		})
 	}
 
-	// Open in append mode for resume, or create a new file
-	if w.download.DownloadedBytes > 0 {
-		w.file, err = os.OpenFile(tempPath, os.O_WRONLY|os.O_APPEND, 0644)
-	} else {
-		w.file, err = os.Create(tempPath)
+	// Segments are written at their offset: the checksum is computed once complete.
+	// A single connection starts its hashes with the bytes already downloaded, and
+	// keeps them only if they match the checkpoint; segments are checked one by one.
+	w.hasher, w.integrity, w.segmentHashes = nil, nil, nil
+	if len(w.download.Segments) == 0 {
+		if err := w.initHasher(tempPath); err != nil {
+			return err
+		}
+		if err := w.verifyCheckpoint(tempPath, cp); err != nil {
+			return err
+		}
+	} else if err := w.verifySegments(tempPath); err != nil {
+		return err
+	}
+	if w.download.DownloadedBytes == 0 {
+		removeCheckpoint(w.download)
 	}
 
+	// Data is written at the download offset: keep the file for resume, or create a new one
+	flags := os.O_WRONLY | os.O_CREATE
+	if w.download.DownloadedBytes == 0 {
+		flags |= os.O_TRUNC
+	}
+	w.file, err = os.OpenFile(tempPath, flags, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open temp file: %w", err)
 	}
 
+	// Reserve the whole file up front: less fragmentation, and a full disk is detected now.
+	// The file size is no longer the downloaded size: DownloadedBytes is the offset.
+	w.preallocated.Store(false)
+	if w.download.FileSize != nil && *w.download.FileSize > 0 {
+		reserved, err := preallocate(w.file, *w.download.FileSize)
+		if err != nil {
+			w.closeFile()
+			if errors.Is(err, syscall.ENOSPC) {
+				return fmt.Errorf("%w: failed to preallocate temp file: %v", ErrInsufficientDiskSpace, err)
+			}
+			return fmt.Errorf("failed to preallocate temp file: %w", err)
+		}
+		w.preallocated.Store(reserved)
+	}
+
 	return nil
 }
 
 // downloadChunk downloads data from the current offset until EOF, pause, or cancel.
 func (w *DownloadWorker) downloadChunk() (completed bool, err error) {
-	reader, contentLength, statusCode, err := w.client.DownloadFile(
+	reader, contentLength, statusCode, err := w.provider.DownloadFile(
+		w.ctx,
 		*w.download.DownloadURL,
 		w.download.DownloadedBytes,
 	)
@@ -400,14 +1212,28 @@ func (w *DownloadWorker) downloadChunk() (completed bool, err error) {
 		return false, fmt.Errorf("failed to start download: %w", err)
 	}
 	defer reader.Close()
+	defer w.trackBody(reader)()
 
 	// Calculate total size from metadata or response headers
+	offset := w.download.DownloadedBytes
 	totalSize := w.calculateTotalSize(statusCode, contentLength)
 
+	// The server ignored the Range header: start over from an empty file
+	if offset > 0 && w.download.DownloadedBytes == 0 {
+		if err := w.restartFile(); err != nil {
+			return false, err
+		}
+	}
+
+	// Record the offset reached, whatever the reason to stop
+	defer w.saveCheckpoint()
+
 	// Read and write with periodic state checks
+	body := w.limitReader(reader)
 	buffer := make([]byte, 64*1024)
 	lastUpdate := time.Now()
 	lastBytes := w.download.DownloadedBytes
+	lastCheckpoint := time.Now()
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -426,17 +1252,27 @@ func (w *DownloadWorker) downloadChunk() (completed bool, err error) {
 		select {
 		case <-ticker.C:
 			w.updateSpeed(&lastUpdate, &lastBytes)
+			if time.Since(lastCheckpoint) >= checkpointInterval {
+				w.saveCheckpoint()
+				lastCheckpoint = time.Now()
+			}
 		default:
 		}
 
 		// Lire
-		n, readErr := reader.Read(buffer)
+		n, readErr := body.Read(buffer)
 
 		if n > 0 {
-			// Write
-			if _, err := w.file.Write(buffer[:n]); err != nil {
+			// Write at the download offset (the file may be preallocated)
+			if _, err := w.file.WriteAt(buffer[:n], w.download.DownloadedBytes); err != nil {
 				return false, fmt.Errorf("failed to write: %w", err)
 			}
+			if w.hasher != nil {
+				w.hasher.Write(buffer[:n])
+			}
+			if w.integrity != nil {
+				w.integrity.Write(buffer[:n])
+			}
 
 			// Update progress
 			w.UpdateDownload(func(d *model.Download) {
@@ -453,30 +1289,61 @@ func (w *DownloadWorker) downloadChunk() (completed bool, err error) {
 		}
 
 		if readErr != nil {
+			// The body was closed by a pause or a cancel request
+			if w.IsCancelled() {
+				return false, errors.New("cancelled")
+			}
+			if w.IsPaused() {
+				return false, nil
+			}
 			return false, readErr
 		}
 	}
 }
 
+// restartFile empties the temp file and the hashes, for a download restarting from zero.
+func (w *DownloadWorker) restartFile() error {
+	if w.hasher != nil {
+		w.hasher.Reset()
+	}
+	if w.integrity != nil {
+		w.integrity.Reset()
+	}
+	removeCheckpoint(w.download)
+
+	w.preallocated.Store(false)
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate temp file: %w", err)
+	}
+	if w.download.FileSize != nil && *w.download.FileSize > 0 {
+		reserved, err := preallocate(w.file, *w.download.FileSize)
+		if err != nil {
+			return fmt.Errorf("failed to preallocate temp file: %w", err)
+		}
+		w.preallocated.Store(reserved)
+	}
+	return nil
+}
+
 // calculateTotalSize resolves the total file size from known metadata or response headers.
 // If the server returns 200 instead of 206 (Partial Content), it does not support Range
 // requests; the offset is reset to zero and the download restarts from the beginning.
 func (w *DownloadWorker) calculateTotalSize(statusCode int, contentLength int64) int64 {
+	// Checked first: the body of a 200 starts at byte 0, whatever the size known
+	if statusCode != http.StatusPartialContent && w.download.DownloadedBytes > 0 {
+		log.Warnf("Server rejected resume for %s", w.download.ID)
+		w.UpdateDownload(func(d *model.Download) {
+			d.DownloadedBytes = 0
+		})
+	}
+
 	if w.download.FileSize != nil && *w.download.FileSize > 0 {
 		return *w.download.FileSize
 	}
 
-	var totalSize int64
+	totalSize := contentLength
 	if statusCode == http.StatusPartialContent {
 		totalSize = w.download.DownloadedBytes + contentLength
-	} else {
-		totalSize = contentLength
-		if w.download.DownloadedBytes > 0 {
-			log.Warnf("Server rejected resume for %s", w.download.ID)
-			w.UpdateDownload(func(d *model.Download) {
-				d.DownloadedBytes = 0
-			})
-		}
 	}
 
 	w.UpdateDownload(func(d *model.Download) {
@@ -486,18 +1353,28 @@ func (w *DownloadWorker) calculateTotalSize(statusCode int, contentLength int64)
 	return totalSize
 }
 
-// updateSpeed recalculates download speed (bytes/sec) since the last call.
+// speedSmoothing is the time constant of the speed moving average: the weight of
+// a sample is divided by e after this duration.
+const speedSmoothing = 5 * time.Second
+
+// updateSpeed folds the download speed (bytes/sec) since the last call into the moving
+// average, and recalculates the ETA.
 func (w *DownloadWorker) updateSpeed(lastUpdate *time.Time, lastBytes *int64) {
 	duration := time.Since(*lastUpdate).Seconds()
 	if duration > 0 {
-		currentBytes := w.download.DownloadedBytes
-		speed := float64(currentBytes-*lastBytes) / duration
 		w.UpdateDownload(func(d *model.Download) {
+			speed := float64(d.DownloadedBytes-*lastBytes) / duration
+			if d.Speed != nil {
+				// Exponentially weighted, by the duration of the sample: a short window barely moves it
+				alpha := 1 - math.Exp(-duration/speedSmoothing.Seconds())
+				speed = *d.Speed + alpha*(speed-*d.Speed)
+			}
 			d.Speed = &speed
+			d.ETA = d.RemainingTime()
+			*lastBytes = d.DownloadedBytes
 		})
-		w.notifyProgress()
+		w.reportProgress()
 		*lastUpdate = time.Now()
-		*lastBytes = currentBytes
 	}
 }
 
@@ -513,24 +1390,89 @@ func (w *DownloadWorker) closeFile() {
 	}
 }
 
-// complete renames the temp file to its final path and marks the download as completed.
+// complete verifies the checksum, renames the temp file to its final path and marks the download as completed.
 func (w *DownloadWorker) complete() error {
 	tempPath, _ := w.download.TempFilePath()
 	finalPath, _ := w.download.FinalFilePath()
 
-	// Remove the final file if it already exists (overwrite)
-	os.Remove(finalPath)
+	// Never expose a file of another size than announced: a checksum is not always available
+	if w.download.FileSize != nil && *w.download.FileSize > 0 && w.download.DownloadedBytes != *w.download.FileSize {
+		return w.fail(fmt.Errorf("downloaded %d bytes, expected %d", w.download.DownloadedBytes, *w.download.FileSize))
+	}
+
+	// Verify the checksum before exposing the file
+	w.UpdateDownload(func(d *model.Download) {
+		d.Status = model.StatusVerifying
+		d.Speed = nil
+		d.ETA = nil
+	})
+	w.notifyProgress()
+
+	if err := w.verifyChecksum(tempPath); err != nil {
+		return w.corrupted(err)
+	}
+
+	// The final file may have appeared since the download was created
+	var outcome *model.ConflictOutcome
+	if _, err := os.Lstat(finalPath); err == nil {
+		policy := w.download.ResolveConflictPolicy(w.conflictPolicy)
+		log.Warnf("Download %s: %s already exists, applying policy %s", w.download.ID, finalPath, policy)
+
+		switch policy {
+		case model.ConflictOverwrite:
+			os.Remove(finalPath)
+			outcome = conflictOutcome(model.OutcomeOverwritten)
+		case model.ConflictRename:
+			// Keep both: pick a name free on disk and unused by the unfinished downloads
+			reserved := map[string]bool{}
+			if w.reservedPaths != nil {
+				if paths, err := w.reservedPaths(w.download.ID); err == nil {
+					reserved = paths
+				} else {
+					log.Warnf("Download %s: %v, renaming by the files on disk only", w.download.ID, err)
+				}
+			}
+			finalPath = utils.AvailablePath(finalPath, func(p string) bool { return reserved[p] })
+			outcome = conflictOutcome(model.OutcomeRenamed)
+		case model.ConflictSkip:
+			// Keep the existing file, the download is done
+			os.Remove(tempPath)
+			tempPath = ""
+			outcome = conflictOutcome(model.OutcomeSkipped)
+		default:
+			// Keep the temp file: the download can be finalized later
+			w.UpdateDownload(func(d *model.Download) {
+				d.ConflictOutcome = conflictOutcome(model.OutcomeFailed)
+			})
+			return w.fail(fmt.Errorf("file already exists: %s", finalPath))
+		}
+	}
 
 	// Rename temp to final path
-	if err := os.Rename(tempPath, finalPath); err != nil {
-		return fmt.Errorf("failed to finalize: %w", err)
+	if tempPath != "" {
+		if err := os.Rename(tempPath, finalPath); err != nil {
+			return fmt.Errorf("failed to finalize: %w", err)
+		}
 	}
+	removeCheckpoint(w.download)
 
 	now := time.Now()
+	fileName := filepath.Base(finalPath)
 	w.UpdateDownload(func(d *model.Download) {
+		if outcome != nil {
+			d.ConflictOutcome = outcome
+		}
+		if fileName != filepath.Base(d.FileName) {
+			d.CustomFileName = &fileName
+		}
 		d.Status = model.StatusCompleted
 		d.Progress = 100
 		d.CompletedAt = &now
+		if d.StartedAt != nil && now.After(*d.StartedAt) {
+			averageSpeed := float64(d.DownloadedBytes) / now.Sub(*d.StartedAt).Seconds()
+			d.AverageSpeed = &averageSpeed
+		}
+		d.ErrorMessage = nil
 	})
 	w.notifyProgress()
 
@@ -538,13 +1480,17 @@ func (w *DownloadWorker) complete() error {
 	return nil
 }
 
+// conflictOutcome returns a pointer to the outcome, as stored on the download.
+func conflictOutcome(outcome model.ConflictOutcome) *model.ConflictOutcome {
+	return &outcome
+}
+
 // fail marks the download as failed and broadcasts the error via SSE.
 func (w *DownloadWorker) fail(err error) error {
 	errMsg := err.Error()
 	w.UpdateDownload(func(d *model.Download) {
 		d.Status = model.StatusFailed
 		d.ErrorMessage = &errMsg
-		d.RetryCount++
 	})
 	w.notifyProgress()
 
@@ -552,10 +1498,24 @@ func (w *DownloadWorker) fail(err error) error {
 	return err
 }
 
+// corrupted marks the download as corrupted. The temp file is kept for inspection.
+func (w *DownloadWorker) corrupted(err error) error {
+	errMsg := err.Error()
+	w.UpdateDownload(func(d *model.Download) {
+		d.Status = model.StatusCorrupted
+		d.ErrorMessage = &errMsg
+	})
+	w.notifyProgress()
+
+	log.Errorf("Download %s corrupted: %v", w.download.ID, err)
+	return err
+}
+
 // cancelCleanup removes the temp file and marks the download as cancelled.
 func (w *DownloadWorker) cancelCleanup() error {
 	tempPath, _ := w.download.TempFilePath()
 	os.Remove(tempPath)
+	removeCheckpoint(w.download)
 
 	w.UpdateDownload(func(d *model.Download) {
 		d.Status = model.StatusCancelled
@@ -566,6 +1526,20 @@ func (w *DownloadWorker) cancelCleanup() error {
 	return nil
 }
 
+// RemoveTempFiles deletes the temp file of a download being deleted, and its checkpoint.
+func RemoveTempFiles(download *model.Download) error {
+	tempPath, err := download.TempFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(tempPath); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to delete temp file %s: %v", tempPath, err)
+	}
+	removeCheckpoint(download)
+
+	return nil
+}
+
 // cleanup closes open files and recovers from panics.
 func (w *DownloadWorker) cleanup() {
 	w.closeFile()
@@ -4,10 +4,12 @@ import (
 	"context"
 	"dlbackend/internal/config"
 	"dlbackend/internal/model"
+	"dlbackend/internal/utils"
 	"dlbackend/pkg/client"
 	"dlbackend/pkg/sse"
 	"errors"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -30,8 +32,8 @@ type MockDownloadRepository struct {
 	mock.Mock
 }
 
-func (m *MockDownloadRepository) List(status []model.DownloadStatus, downloadTypes []model.DownloadType, page, limit int) ([]model.Download, int64, error) {
-	args := m.Called(status, downloadTypes, page, limit)
+func (m *MockDownloadRepository) List(status []model.DownloadStatus, downloadTypes []model.DownloadType, sort model.DownloadSort, page, limit int) ([]model.Download, int64, error) {
+	args := m.Called(status, downloadTypes, sort, page, limit)
 	if args.Get(0) == nil {
 		return nil, args.Get(1).(int64), args.Error(2)
 	}
@@ -43,6 +45,46 @@ func (m *MockDownloadRepository) Create(download *model.Download) error {
 	return args.Error(0)
 }
 
+func (m *MockDownloadRepository) GetByGroupID(groupID string) ([]model.Download, error) {
+	args := m.Called(groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Download), args.Error(1)
+}
+
+func (m *MockDownloadRepository) GetByFileURL(fileURL string) ([]model.Download, error) {
+	args := m.Called(fileURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Download), args.Error(1)
+}
+
+func (m *MockDownloadRepository) GetByOneFichierFileID(fileID string) ([]model.Download, error) {
+	args := m.Called(fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Download), args.Error(1)
+}
+
+func (m *MockDownloadRepository) GetByChecksum(checksum string) ([]model.Download, error) {
+	args := m.Called(checksum)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Download), args.Error(1)
+}
+
+func (m *MockDownloadRepository) GetUnfinished() ([]model.Download, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Download), args.Error(1)
+}
+
 func (m *MockDownloadRepository) GetByID(id string) (*model.Download, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -64,6 +106,14 @@ func (m *MockDownloadRepository) GetActive() ([]model.Download, error) {
 	return args.Get(0).([]model.Download), args.Error(1)
 }
 
+func (m *MockDownloadRepository) GetPending() ([]model.Download, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Download), args.Error(1)
+}
+
 func (m *MockDownloadRepository) Delete(id string) error {
 	args := m.Called(id)
 	return args.Error(0)
@@ -153,27 +203,36 @@ func (m *MockSSEManager) OnEvent(eventName string, handlers ...sse.OnEventHandle
 // MOCK ONE FICHIER CLIENT
 // ============================================================================
 
-type MockOneFichierClient struct {
+// MockProvider ignores the context: expectations are set on the other arguments.
+type MockProvider struct {
 	mock.Mock
 }
 
-func (m *MockOneFichierClient) GetFileInfo(fileURL string) (*client.OneFichierInfoResponse, error) {
-	args := m.Called(fileURL)
+func (m *MockProvider) Name() string {
+	return client.ProviderOneFichier
+}
+
+func (m *MockProvider) Match(fileURL string) bool {
+	return true
+}
+
+func (m *MockProvider) GetFileInfo(_ context.Context, fileURL string, password string) (*client.FileInfo, error) {
+	args := m.Called(fileURL, password)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*client.OneFichierInfoResponse), args.Error(1)
+	return args.Get(0).(*client.FileInfo), args.Error(1)
 }
 
-func (m *MockOneFichierClient) GetDownloadToken(fileURL string) (*client.OneFichierTokenResponse, error) {
-	args := m.Called(fileURL)
+func (m *MockProvider) ResolveDownloadURL(_ context.Context, fileURL string, password string) (*client.DownloadLink, error) {
+	args := m.Called(fileURL, password)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*client.OneFichierTokenResponse), args.Error(1)
+	return args.Get(0).(*client.DownloadLink), args.Error(1)
 }
 
-func (m *MockOneFichierClient) DownloadFile(downloadURL string, offset int64) (io.ReadCloser, int64, int, error) {
+func (m *MockProvider) DownloadFile(_ context.Context, downloadURL string, offset int64) (io.ReadCloser, int64, int, error) {
 	args := m.Called(downloadURL, offset)
 	if args.Get(0) == nil {
 		return nil, args.Get(1).(int64), args.Get(2).(int), args.Error(3)
@@ -181,6 +240,14 @@ func (m *MockOneFichierClient) DownloadFile(downloadURL string, offset int64) (i
 	return args.Get(0).(io.ReadCloser), args.Get(1).(int64), args.Get(2).(int), args.Error(3)
 }
 
+func (m *MockProvider) DownloadRange(_ context.Context, downloadURL string, start int64, end int64) (io.ReadCloser, int64, int, error) {
+	args := m.Called(downloadURL, start, end)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Get(2).(int), args.Error(3)
+	}
+	return args.Get(0).(io.ReadCloser), args.Get(1).(int64), args.Get(2).(int), args.Error(3)
+}
+
 // ============================================================================
 // HELPER: Mock ReadCloser
 // ============================================================================
@@ -209,6 +276,97 @@ func setupTestConfig(t *testing.T) string {
 	return tempDir
 }
 
+// testWorker is a worker built by newTestWorker, with its mocks.
+type testWorker struct {
+	*DownloadWorker
+	mockRepo   *MockDownloadRepository
+	mockClient *MockProvider
+	mockSSE    *MockSSEManager
+	tempPath   string
+}
+
+// testWorkerSetup is the download of a test worker, changed by the testWorkerOptions.
+type testWorkerSetup struct {
+	download *model.Download
+	content  *string           // Content of the temp file, nil for no temp file
+	written  *[]model.Download // Copies of the DB writes, nil to accept them silently
+}
+
+type testWorkerOption func(setup *testWorkerSetup)
+
+func withChecksum(checksum string) testWorkerOption {
+	return func(setup *testWorkerSetup) { setup.download.Checksum = &checksum }
+}
+
+func withSize(size int64) testWorkerOption {
+	return func(setup *testWorkerSetup) { setup.download.FileSize = &size }
+}
+
+func withDownloaded(downloaded int64) testWorkerOption {
+	return func(setup *testWorkerSetup) { setup.download.DownloadedBytes = downloaded }
+}
+
+func withDownloadURL(downloadURL string) testWorkerOption {
+	return func(setup *testWorkerSetup) { setup.download.DownloadURL = &downloadURL }
+}
+
+func withSegments(segments []model.DownloadSegment) testWorkerOption {
+	return func(setup *testWorkerSetup) { setup.download.Segments = segments }
+}
+
+// withContent writes the temp file of the download, removed with its checkpoint after the test.
+func withContent(content string) testWorkerOption {
+	return func(setup *testWorkerSetup) { setup.content = &content }
+}
+
+// recordWrites appends a copy of the download to written on each DB write.
+func recordWrites(written *[]model.Download) testWorkerOption {
+	return func(setup *testWorkerSetup) { setup.written = written }
+}
+
+// newTestWorker returns a worker of the movie download "test-id" (test.txt, downloading),
+// with mocks accepting any DB write and SSE event. Call setupTestConfig first.
+func newTestWorker(t *testing.T, opts ...testWorkerOption) *testWorker {
+	t.Helper()
+
+	setup := &testWorkerSetup{download: &model.Download{
+		ID:       "test-id",
+		FileName: "test.txt",
+		Status:   model.StatusDownloading,
+		Type:     model.TypeMovie,
+	}}
+	for _, opt := range opts {
+		opt(setup)
+	}
+
+	w := &testWorker{
+		mockRepo:   new(MockDownloadRepository),
+		mockClient: new(MockProvider),
+		mockSSE:    new(MockSSEManager),
+	}
+	update := w.mockRepo.On("Update", mock.Anything).Return(nil)
+	if setup.written != nil {
+		update.Run(func(args mock.Arguments) {
+			*setup.written = append(*setup.written, *args.Get(0).(*model.Download))
+		})
+	}
+	w.mockSSE.On("SendEvent", mock.Anything, mock.Anything).Return(nil)
+
+	download := setup.download
+	if setup.content != nil {
+		w.tempPath, _ = download.TempFilePath()
+		require.NoError(t, os.MkdirAll(filepath.Dir(w.tempPath), 0755))
+		require.NoError(t, os.WriteFile(w.tempPath, []byte(*setup.content), 0644))
+		t.Cleanup(func() {
+			os.Remove(w.tempPath)
+			removeCheckpoint(download)
+		})
+	}
+
+	w.DownloadWorker = NewDownloadWorker(context.Background(), download, w.mockRepo, w.mockClient, w.mockSSE)
+	return w
+}
+
 // waitForWorkerCompletion blocks until the worker is removed from the manager map (execution complete).
 func waitForWorkerCompletion(t *testing.T, manager *DownloadManager, downloadID string, timeout time.Duration) {
 	deadline := time.Now().Add(timeout)
@@ -242,14 +400,14 @@ func TestDownloadManager_Start(t *testing.T) {
 		mockRepo := new(MockDownloadRepository)
 		mockSettingsRepo := new(MockSettingsRepository)
 		mockSSE := new(MockSSEManager)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 
 		mockSettingsRepo.On("Get").Return(&model.Settings{
 			APIKey1fichier: "test-api-key",
 		}, nil)
 
 		// Mock for stepGetFileInfo
-		mockClient.On("GetFileInfo", "https://1fichier.com/test").Return(&client.OneFichierInfoResponse{
+		mockClient.On("GetFileInfo", "https://1fichier.com/test", "").Return(&client.FileInfo{
 			Filename:    "test.pdf",
 			Size:        int64(1024),
 			Checksum:    "abc123",
@@ -257,8 +415,9 @@ func TestDownloadManager_Start(t *testing.T) {
 		}, nil)
 
 		// Mock for stepGetDownloadToken
-		mockClient.On("GetDownloadToken", "https://1fichier.com/test").Return(&client.OneFichierTokenResponse{
+		mockClient.On("ResolveDownloadURL", "https://1fichier.com/test", "").Return(&client.DownloadLink{
 			URL: "https://download.1fichier.com/xyz",
+			TTL: 5 * time.Minute,
 		}, nil)
 
 		// Mock repo.Update for all calls
@@ -269,7 +428,7 @@ func TestDownloadManager_Start(t *testing.T) {
 		// Mock SSE.SendEvent
 		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
 
-		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, mockSSE)
+		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, nil, mockSSE)
 
 		download := &model.Download{
 			ID:      "test-id",
@@ -311,7 +470,7 @@ func TestDownloadManager_Start(t *testing.T) {
 			APIKey1fichier: "",
 		}, nil)
 
-		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, mockSSE)
+		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, client.Providers{new(MockProvider)}, mockSSE)
 
 		download := &model.Download{
 			ID:      "test-id",
@@ -328,6 +487,29 @@ func TestDownloadManager_Start(t *testing.T) {
 		assert.False(t, exists)
 	})
 
+	t.Run("download paused after the queue was read is not started", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSSE := new(MockSSEManager)
+
+		mockSettingsRepo.On("Get").Return(&model.Settings{
+			APIKey1fichier:         "test-api-key",
+			MaxConcurrentDownloads: 1,
+		}, nil)
+		mockRepo.On("GetPending").Return([]model.Download{
+			{ID: "paused", Status: model.StatusPending, Type: model.TypeMovie},
+		}, nil)
+		mockRepo.On("GetByID", "paused").Return(&model.Download{ID: "paused", Status: model.StatusPaused, Type: model.TypeMovie}, nil)
+
+		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, nil, mockSSE)
+		manager.schedule()
+
+		_, running := manager.workers.Load("paused")
+		assert.False(t, running)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
 	t.Run("settings error", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
@@ -336,7 +518,7 @@ func TestDownloadManager_Start(t *testing.T) {
 
 		mockSettingsRepo.On("Get").Return(nil, errors.New("db error"))
 
-		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, mockSSE)
+		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, nil, mockSSE)
 
 		download := &model.Download{
 			ID:      "test-id",
@@ -354,13 +536,92 @@ func TestDownloadManager_Start(t *testing.T) {
 	})
 }
 
+func TestDownloadManager_Schedule(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("limit reached", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockClient := new(MockProvider)
+		mockSSE := new(MockSSEManager)
+
+		mockSettingsRepo.On("Get").Return(&model.Settings{
+			APIKey1fichier:         "test-api-key",
+			MaxConcurrentDownloads: 1,
+		}, nil)
+
+		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, nil, mockSSE)
+
+		running := NewDownloadWorker(ctx, &model.Download{ID: "running", Type: model.TypeMovie}, mockRepo, mockClient, mockSSE)
+		manager.workers.Store("running", running)
+
+		manager.schedule()
+
+		// The queue must not be read while no slot is free
+		mockRepo.AssertNotCalled(t, "GetPending")
+	})
+
+	t.Run("paused worker releases its slot", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockClient := new(MockProvider)
+		mockSSE := new(MockSSEManager)
+
+		mockSettingsRepo.On("Get").Return(&model.Settings{
+			APIKey1fichier:         "test-api-key",
+			MaxConcurrentDownloads: 1,
+		}, nil)
+
+		paused := NewDownloadWorker(ctx, &model.Download{ID: "paused", Type: model.TypeMovie}, mockRepo, mockClient, mockSSE)
+		paused.Pause()
+
+		// The only pending download is already handled by a worker: nothing to start
+		mockRepo.On("GetPending").Return([]model.Download{
+			{ID: "paused", Status: model.StatusPending, Type: model.TypeMovie},
+		}, nil)
+
+		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, nil, mockSSE)
+		manager.workers.Store("paused", paused)
+
+		assert.Equal(t, 0, manager.activeCount())
+
+		manager.schedule()
+
+		mockRepo.AssertCalled(t, "GetPending")
+		mockSettingsRepo.AssertNumberOfCalls(t, "Get", 1)
+	})
+
+	t.Run("settings error", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSSE := new(MockSSEManager)
+
+		mockSettingsRepo.On("Get").Return(nil, errors.New("db error"))
+
+		manager := NewDownloadManager(ctx, mockRepo, mockSettingsRepo, nil, mockSSE)
+		manager.schedule()
+
+		mockRepo.AssertNotCalled(t, "GetPending")
+	})
+
+	t.Run("schedule is non-blocking", func(t *testing.T) {
+		manager := NewDownloadManager(context.Background(), nil, nil, nil, nil)
+		manager.Schedule()
+		manager.Schedule()
+		assert.Len(t, manager.wake, 1)
+	})
+}
+
 func TestDownloadManager_Pause(t *testing.T) {
 	setupTestConfig(t)
 
 	t.Run("success", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
 		manager := &DownloadManager{
@@ -381,7 +642,9 @@ func TestDownloadManager_Pause(t *testing.T) {
 
 	t.Run("download not found", func(t *testing.T) {
 		ctx := context.Background()
-		manager := &DownloadManager{ctx: ctx}
+		mockRepo := new(MockDownloadRepository)
+		mockRepo.On("GetByID", "non-existent").Return(nil, errors.New("record not found"))
+		manager := &DownloadManager{ctx: ctx, repo: mockRepo}
 
 		err := manager.Pause("non-existent")
 		assert.Error(t, err)
@@ -395,10 +658,13 @@ func TestDownloadManager_Resume(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
-		manager := &DownloadManager{ctx: ctx}
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSettingsRepo.On("Get").Return(&model.Settings{MaxConcurrentDownloads: 1}, nil)
+
+		manager := &DownloadManager{ctx: ctx, settingsRepo: mockSettingsRepo}
 
 		download := &model.Download{
 			ID:   "test-id",
@@ -413,22 +679,263 @@ func TestDownloadManager_Resume(t *testing.T) {
 		assert.False(t, worker.IsPaused())
 	})
 
+	t.Run("waits for a free slot", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSSE := new(MockSSEManager)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSettingsRepo.On("Get").Return(&model.Settings{MaxConcurrentDownloads: 1}, nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+		manager := &DownloadManager{ctx: ctx, repo: mockRepo, settingsRepo: mockSettingsRepo}
+
+		// A is paused, B took its slot
+		paused := NewDownloadWorker(ctx, &model.Download{ID: "a", Status: model.StatusPaused}, mockRepo, new(MockProvider), mockSSE)
+		paused.Pause()
+		running := NewDownloadWorker(ctx, &model.Download{ID: "b"}, mockRepo, new(MockProvider), mockSSE)
+		manager.workers.Store("a", paused)
+		manager.workers.Store("b", running)
+
+		require.NoError(t, manager.Resume("a"))
+		assert.True(t, paused.IsPaused())
+		assert.Equal(t, model.StatusPending, paused.download.Status)
+		assert.Equal(t, 0, manager.resumeQueued(max(1-manager.activeCount(), 0)))
+
+		// B is done: A resumes in its slot
+		manager.workers.Delete("b")
+		assert.Equal(t, 1, manager.resumeQueued(1-manager.activeCount()))
+		assert.False(t, paused.IsPaused())
+		assert.Empty(t, manager.resumeQueue)
+	})
+
+	t.Run("pause while waiting for a slot", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockSettingsRepo := new(MockSettingsRepository)
+		mockSSE := new(MockSSEManager)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSettingsRepo.On("Get").Return(&model.Settings{MaxConcurrentDownloads: 1}, nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+		manager := &DownloadManager{ctx: ctx, repo: mockRepo, settingsRepo: mockSettingsRepo}
+
+		paused := NewDownloadWorker(ctx, &model.Download{ID: "a", Status: model.StatusPaused}, mockRepo, new(MockProvider), mockSSE)
+		paused.Pause()
+		manager.workers.Store("a", paused)
+		manager.workers.Store("b", NewDownloadWorker(ctx, &model.Download{ID: "b"}, mockRepo, new(MockProvider), mockSSE))
+
+		require.NoError(t, manager.Resume("a"))
+		require.NoError(t, manager.Pause("a"))
+		assert.Equal(t, model.StatusPaused, paused.download.Status)
+		assert.Empty(t, manager.resumeQueue)
+	})
+
 	t.Run("download not found", func(t *testing.T) {
 		ctx := context.Background()
-		manager := &DownloadManager{ctx: ctx}
+		mockRepo := new(MockDownloadRepository)
+		mockRepo.On("GetByID", "non-existent").Return(nil, errors.New("record not found"))
+		manager := &DownloadManager{ctx: ctx, repo: mockRepo}
 
 		err := manager.Resume("non-existent")
 		assert.Error(t, err)
 	})
 }
 
+func TestDownloadManager_ResumeWithoutWorker(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("paused download is queued again", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockSSE := new(MockSSEManager)
+
+		download := &model.Download{ID: "test-id", Status: model.StatusPaused, Type: model.TypeMovie}
+		mockRepo.On("GetByID", "test-id").Return(download, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+		manager := NewDownloadManager(ctx, mockRepo, nil, nil, mockSSE)
+
+		err := manager.Resume("test-id")
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusPending, download.Status)
+		assert.Len(t, manager.wake, 1)
+	})
+
+	t.Run("completed download can't be resumed", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+
+		download := &model.Download{ID: "test-id", Status: model.StatusCompleted, Type: model.TypeMovie}
+		mockRepo.On("GetByID", "test-id").Return(download, nil)
+
+		manager := NewDownloadManager(ctx, mockRepo, nil, nil, nil)
+
+		err := manager.Resume("test-id")
+		assert.ErrorIs(t, err, ErrInvalidTransition)
+		assert.Equal(t, model.StatusCompleted, download.Status)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+}
+
+func TestDownloadManager_SetSpeedLimit(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("queued download is updated in DB", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		download := &model.Download{ID: "test-id", Status: model.StatusPending, Type: model.TypeMovie}
+		mockRepo.On("GetByID", "test-id").Return(download, nil)
+		mockRepo.On("Update", download).Return(nil)
+
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, nil)
+
+		limit := int64(1024)
+		require.NoError(t, manager.SetSpeedLimit("test-id", &limit))
+		require.NotNil(t, download.SpeedLimit)
+		assert.Equal(t, int64(1024), *download.SpeedLimit)
+
+		// Zero removes the limit
+		zero := int64(0)
+		require.NoError(t, manager.SetSpeedLimit("test-id", &zero))
+		assert.Nil(t, download.SpeedLimit)
+	})
+
+	t.Run("running worker limiter is updated", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		mockSSE := new(MockSSEManager)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, mockSSE)
+		download := &model.Download{ID: "test-id", Type: model.TypeMovie}
+		worker := NewDownloadWorker(context.Background(), download, mockRepo, nil, mockSSE)
+		manager.workers.Store("test-id", worker)
+
+		limit := int64(2048)
+		require.NoError(t, manager.SetSpeedLimit("test-id", &limit))
+		assert.Equal(t, int64(2048), worker.limiter.Rate())
+		assert.Equal(t, int64(2048), *worker.download.SpeedLimit)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("download not found", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		mockRepo.On("GetByID", "unknown").Return(nil, errors.New("record not found"))
+
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, nil)
+
+		err := manager.SetSpeedLimit("unknown", nil)
+		assert.EqualError(t, err, "download not found")
+	})
+}
+
+func TestDownloadManager_SetPriority(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("queued download is updated in DB", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		download := &model.Download{ID: "test-id", Status: model.StatusPending, Type: model.TypeMovie}
+		mockRepo.On("GetByID", "test-id").Return(download, nil)
+		mockRepo.On("Update", download).Return(nil)
+
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, nil)
+
+		require.NoError(t, manager.SetPriority("test-id", 5))
+		assert.Equal(t, 5, download.Priority)
+	})
+
+	t.Run("running worker is updated", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		mockSSE := new(MockSSEManager)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, mockSSE)
+		download := &model.Download{ID: "test-id", Type: model.TypeMovie}
+		worker := NewDownloadWorker(context.Background(), download, mockRepo, nil, mockSSE)
+		manager.workers.Store("test-id", worker)
+
+		require.NoError(t, manager.SetPriority("test-id", -1))
+		assert.Equal(t, -1, worker.download.Priority)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("download not found", func(t *testing.T) {
+		mockRepo := new(MockDownloadRepository)
+		mockRepo.On("GetByID", "unknown").Return(nil, errors.New("record not found"))
+
+		manager := NewDownloadManager(context.Background(), mockRepo, nil, nil, nil)
+
+		assert.ErrorIs(t, manager.SetPriority("unknown", 1), ErrDownloadNotFound)
+	})
+}
+
+func TestDownloadManager_Restore(t *testing.T) {
+	setupTestConfig(t)
+
+	ctx := context.Background()
+	mockRepo := new(MockDownloadRepository)
+
+	// Interrupted download with a consistent temp file
+	resumable := model.Download{
+		ID:              "resumable",
+		FileName:        "resumable.txt",
+		Status:          model.StatusDownloading,
+		DownloadedBytes: 4,
+		Type:            model.TypeMovie,
+	}
+	tempPath, _ := resumable.TempFilePath()
+	os.MkdirAll(filepath.Dir(tempPath), 0755)
+	os.WriteFile(tempPath, []byte("data"), 0644)
+
+	// Interrupted download without temp file
+	lost := model.Download{
+		ID:              "lost",
+		FileName:        "lost.txt",
+		Status:          model.StatusRequestingToken,
+		DownloadedBytes: 10,
+		Progress:        50,
+		Type:            model.TypeMovie,
+	}
+
+	// Queued download, left untouched
+	pending := model.Download{
+		ID:     "pending",
+		Status: model.StatusPending,
+		Type:   model.TypeMovie,
+	}
+
+	mockRepo.On("GetActive").Return([]model.Download{resumable, lost, pending}, nil)
+
+	restored := map[string]model.Download{}
+	mockRepo.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		d := args.Get(0).(*model.Download)
+		restored[d.ID] = *d
+	}).Return(nil)
+
+	manager := NewDownloadManager(ctx, mockRepo, nil, nil, nil)
+	err := manager.Restore()
+	require.NoError(t, err)
+
+	assert.Len(t, restored, 2)
+
+	assert.Equal(t, model.StatusPending, restored["resumable"].Status)
+	assert.Equal(t, int64(4), restored["resumable"].DownloadedBytes)
+
+	assert.Equal(t, model.StatusPaused, restored["lost"].Status)
+	assert.Equal(t, int64(0), restored["lost"].DownloadedBytes)
+	assert.Equal(t, float64(0), restored["lost"].Progress)
+	assert.NotNil(t, restored["lost"].ErrorMessage)
+}
+
 func TestDownloadManager_Cancel(t *testing.T) {
 	setupTestConfig(t)
 
 	t.Run("success", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
 		manager := &DownloadManager{ctx: ctx}
@@ -447,7 +954,9 @@ func TestDownloadManager_Cancel(t *testing.T) {
 
 	t.Run("download not found", func(t *testing.T) {
 		ctx := context.Background()
-		manager := &DownloadManager{ctx: ctx}
+		mockRepo := new(MockDownloadRepository)
+		mockRepo.On("GetByID", "non-existent").Return(nil, errors.New("record not found"))
+		manager := &DownloadManager{ctx: ctx, repo: mockRepo}
 
 		err := manager.Cancel("non-existent")
 		assert.Error(t, err)
@@ -463,7 +972,7 @@ func TestDownloadWorker_StateManagement(t *testing.T) {
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	download := &model.Download{
@@ -495,10 +1004,131 @@ func TestDownloadWorker_StateManagement(t *testing.T) {
 	})
 
 	t.Run("cancel is idempotent", func(t *testing.T) {
-		worker.Cancel()
-		worker.Cancel()
+		assert.NoError(t, worker.Cancel())
+		assert.NoError(t, worker.Cancel())
 		assert.True(t, worker.IsCancelled())
 	})
+
+	t.Run("cancelled is final", func(t *testing.T) {
+		err := worker.Resume()
+		assert.ErrorIs(t, err, ErrInvalidTransition)
+		assert.EqualError(t, err, "invalid state transition from CANCELLED to RUNNING")
+		assert.ErrorIs(t, worker.Pause(), ErrInvalidTransition)
+		assert.True(t, worker.IsCancelled())
+	})
+
+	t.Run("done is final", func(t *testing.T) {
+		done := NewDownloadWorker(ctx, &model.Download{ID: "done", Type: model.TypeMovie}, mockRepo, mockClient, mockSSE)
+		_, err := done.transition(StateDone)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, done.Pause(), ErrInvalidTransition)
+		assert.ErrorIs(t, done.Resume(), ErrInvalidTransition)
+		assert.ErrorIs(t, done.Cancel(), ErrInvalidTransition)
+	})
+}
+
+func TestDownloadWorker_WaitWhilePaused(t *testing.T) {
+	setupTestConfig(t)
+
+	newPausedWorker := func(ctx context.Context) *DownloadWorker {
+		worker := NewDownloadWorker(ctx, &model.Download{ID: "test-id", Type: model.TypeMovie}, nil, nil, nil)
+		require.NoError(t, worker.Pause())
+		return worker
+	}
+	wait := func(worker *DownloadWorker) chan bool {
+		result := make(chan bool, 1)
+		go func() { result <- worker.waitWhilePaused() }()
+		return result
+	}
+
+	t.Run("blocks until resumed", func(t *testing.T) {
+		worker := newPausedWorker(context.Background())
+		result := wait(worker)
+
+		select {
+		case <-result:
+			t.Fatal("waitWhilePaused returned while paused")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.NoError(t, worker.Resume())
+		assert.True(t, <-result)
+	})
+
+	t.Run("released by cancel", func(t *testing.T) {
+		worker := newPausedWorker(context.Background())
+		result := wait(worker)
+
+		require.NoError(t, worker.Cancel())
+		assert.False(t, <-result)
+	})
+
+	t.Run("released by shutdown", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		worker := newPausedWorker(ctx)
+		result := wait(worker)
+
+		cancel()
+		assert.False(t, <-result)
+	})
+}
+
+// blockingReadCloser blocks on Read until closed.
+type blockingReadCloser struct {
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (b *blockingReadCloser) Read(p []byte) (int, error) {
+	<-b.closed
+	return 0, errors.New("read on closed body")
+}
+
+func (b *blockingReadCloser) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
+
+func TestDownloadWorker_PauseClosesBody(t *testing.T) {
+	setupTestConfig(t)
+
+	mockRepo := new(MockDownloadRepository)
+	mockClient := new(MockProvider)
+	mockSSE := new(MockSSEManager)
+	mockRepo.On("Update", mock.Anything).Return(nil)
+	mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+	downloadURL := "https://download.1fichier.com/test"
+	download := &model.Download{ID: "test-id", FileName: "test.txt", DownloadURL: &downloadURL, Type: model.TypeMovie}
+	worker := NewDownloadWorker(context.Background(), download, mockRepo, mockClient, mockSSE)
+
+	body := &blockingReadCloser{closed: make(chan struct{})}
+	mockClient.On("DownloadFile", downloadURL, int64(0)).Return(body, int64(100), http.StatusOK, nil)
+
+	require.NoError(t, worker.prepareFile())
+	defer worker.closeFile()
+
+	type result struct {
+		completed bool
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		completed, err := worker.downloadChunk()
+		done <- result{completed, err}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, worker.Pause())
+
+	select {
+	case r := <-done:
+		assert.NoError(t, r.err)
+		assert.False(t, r.completed)
+	case <-time.After(time.Second):
+		t.Fatal("pause did not interrupt the body read")
+	}
 }
 
 func TestDownloadWorker_UpdateDownload(t *testing.T) {
@@ -506,7 +1136,7 @@ func TestDownloadWorker_UpdateDownload(t *testing.T) {
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	download := &model.Download{
@@ -538,14 +1168,14 @@ func TestDownloadWorker_StepGetFileInfo(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
 		fileSize := int64(1024)
 		checksum := "abc123"
 		contentType := "application/pdf"
 
-		mockClient.On("GetFileInfo", "https://1fichier.com/test").Return(&client.OneFichierInfoResponse{
+		mockClient.On("GetFileInfo", "https://1fichier.com/test", "").Return(&client.FileInfo{
 			Filename:    "test.pdf",
 			Size:        fileSize,
 			Checksum:    checksum,
@@ -573,13 +1203,41 @@ func TestDownloadWorker_StepGetFileInfo(t *testing.T) {
 		mockClient.AssertExpectations(t)
 	})
 
+	t.Run("password protected file", func(t *testing.T) {
+		config.Cfg.SecretKey = "secret"
+		defer func() { config.Cfg.SecretKey = "" }()
+
+		mockRepo := new(MockDownloadRepository)
+		mockClient := new(MockProvider)
+		mockSSE := new(MockSSEManager)
+
+		mockClient.On("GetFileInfo", "https://1fichier.com/test", "p4ssw0rd").Return(&client.FileInfo{
+			Filename: "test.pdf",
+		}, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+		password, err := utils.EncryptSecret("p4ssw0rd")
+		require.NoError(t, err)
+		download := &model.Download{
+			ID:       "test-id",
+			FileURL:  "https://1fichier.com/test",
+			Password: &password,
+			Type:     model.TypeMovie,
+		}
+		worker := NewDownloadWorker(context.Background(), download, mockRepo, mockClient, mockSSE)
+
+		require.NoError(t, worker.stepGetFileInfo())
+		mockClient.AssertExpectations(t)
+	})
+
 	t.Run("client error", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
-		mockClient.On("GetFileInfo", mock.Anything).Return(nil, errors.New("api error"))
+		mockClient.On("GetFileInfo", mock.Anything, mock.Anything).Return(nil, errors.New("api error"))
 		mockRepo.On("Update", mock.Anything).Return(nil)
 		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
 
@@ -602,11 +1260,12 @@ func TestDownloadWorker_StepGetDownloadToken(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
-		mockClient.On("GetDownloadToken", "https://1fichier.com/test").Return(&client.OneFichierTokenResponse{
+		mockClient.On("ResolveDownloadURL", "https://1fichier.com/test", "").Return(&client.DownloadLink{
 			URL: "https://download.1fichier.com/xyz",
+			TTL: 5 * time.Minute,
 		}, nil)
 
 		mockRepo.On("Update", mock.Anything).Return(nil)
@@ -629,13 +1288,41 @@ func TestDownloadWorker_StepGetDownloadToken(t *testing.T) {
 		mockClient.AssertExpectations(t)
 	})
 
+	t.Run("link without expiry", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockClient := new(MockProvider)
+		mockSSE := new(MockSSEManager)
+
+		mockClient.On("ResolveDownloadURL", "https://example.com/movie.mkv", "").Return(&client.DownloadLink{
+			URL: "https://example.com/movie.mkv",
+		}, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+		expiresAt := time.Now().Add(-time.Minute)
+		download := &model.Download{
+			ID:                   "test-id",
+			FileURL:              "https://example.com/movie.mkv",
+			Provider:             client.ProviderDirect,
+			DownloadURLExpiresAt: &expiresAt,
+			Type:                 model.TypeMovie,
+		}
+		worker := NewDownloadWorker(ctx, download, mockRepo, mockClient, mockSSE)
+
+		require.NoError(t, worker.stepGetDownloadToken())
+		assert.Equal(t, "https://example.com/movie.mkv", *download.DownloadURL)
+		assert.Nil(t, download.DownloadURLExpiresAt)
+		assert.False(t, worker.isDownloadURLExpired())
+	})
+
 	t.Run("client error", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
-		mockClient.On("GetDownloadToken", mock.Anything).Return(nil, errors.New("token error"))
+		mockClient.On("ResolveDownloadURL", mock.Anything, mock.Anything).Return(nil, errors.New("token error"))
 		mockRepo.On("Update", mock.Anything).Return(nil)
 		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
 
@@ -657,7 +1344,7 @@ func TestDownloadWorker_CalculateTotalSize(t *testing.T) {
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	t.Run("with existing file size", func(t *testing.T) {
@@ -698,6 +1385,58 @@ func TestDownloadWorker_CalculateTotalSize(t *testing.T) {
 		assert.Equal(t, int64(1000), size)
 		assert.Equal(t, int64(0), download.DownloadedBytes)
 	})
+
+	t.Run("full content resets offset with existing file size", func(t *testing.T) {
+		fileSize := int64(1000)
+		download := &model.Download{
+			ID:              "test-id",
+			FileSize:        &fileSize,
+			DownloadedBytes: 500,
+			Type:            model.TypeMovie,
+		}
+		worker := NewDownloadWorker(ctx, download, mockRepo, mockClient, mockSSE)
+
+		size := worker.calculateTotalSize(http.StatusOK, 1000)
+		assert.Equal(t, int64(1000), size)
+		assert.Equal(t, int64(0), download.DownloadedBytes)
+	})
+}
+
+func TestDownloadWorker_ResumeRejected(t *testing.T) {
+	setupTestConfig(t)
+	downloadURL := "https://download.1fichier.com/test"
+
+	t.Run("full body restarts the file from zero", func(t *testing.T) {
+		worker := newTestWorker(t, withSize(11), withDownloaded(6), withDownloadURL(downloadURL), withContent("hello "))
+		worker.mockClient.On("DownloadFile", downloadURL, int64(6)).Return(
+			&MockReadCloser{reader: strings.NewReader("hello world")}, int64(11), http.StatusOK, nil,
+		)
+
+		require.NoError(t, worker.prepareFile())
+		completed, err := worker.downloadChunk()
+		worker.closeFile()
+		require.NoError(t, err)
+		require.True(t, completed)
+
+		assert.Equal(t, int64(11), worker.download.DownloadedBytes)
+		content, err := os.ReadFile(worker.tempPath)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(content))
+	})
+
+	t.Run("short body is not completed", func(t *testing.T) {
+		worker := newTestWorker(t, withSize(11), withDownloadURL(downloadURL))
+		worker.retryPolicy = RetryPolicy{}
+		worker.mockClient.On("DownloadFile", downloadURL, int64(0)).Return(
+			&MockReadCloser{reader: strings.NewReader("hello")}, int64(11), http.StatusOK, nil,
+		)
+		tempPath, _ := worker.download.TempFilePath()
+		defer os.Remove(tempPath)
+
+		err := worker.stepDownload()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, int64(5), worker.download.DownloadedBytes)
+	})
 }
 
 func TestDownloadWorker_PrepareFile(t *testing.T) {
@@ -705,7 +1444,7 @@ func TestDownloadWorker_PrepareFile(t *testing.T) {
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	t.Run("create new file", func(t *testing.T) {
@@ -778,7 +1517,7 @@ func TestDownloadWorker_DownloadChunk(t *testing.T) {
 	t.Run("successful download", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
 		downloadURL := "https://download.1fichier.com/test"
@@ -829,7 +1568,7 @@ func TestDownloadWorker_DownloadChunk(t *testing.T) {
 	t.Run("cancelled during download", func(t *testing.T) {
 		ctx := context.Background()
 		mockRepo := new(MockDownloadRepository)
-		mockClient := new(MockOneFichierClient)
+		mockClient := new(MockProvider)
 		mockSSE := new(MockSSEManager)
 
 		downloadURL := "https://download.1fichier.com/test"
@@ -860,12 +1599,114 @@ func TestDownloadWorker_DownloadChunk(t *testing.T) {
 	})
 }
 
+func TestDownloadWorker_TokenRenewal(t *testing.T) {
+	setupTestConfig(t)
+
+	t.Run("renew on expired link response", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockClient := new(MockProvider)
+		mockSSE := new(MockSSEManager)
+
+		oldURL := "https://download.1fichier.com/old"
+		newURL := "https://download.1fichier.com/new"
+		expiresAt := time.Now().Add(time.Minute)
+
+		download := &model.Download{
+			ID:                   "test-id",
+			FileURL:              "https://1fichier.com/?test",
+			FileName:             "test.txt",
+			DownloadURL:          &oldURL,
+			DownloadURLExpiresAt: &expiresAt,
+			Type:                 model.TypeMovie,
+		}
+
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+		mockClient.On("DownloadFile", oldURL, int64(0)).Return(nil, int64(0), 0, &client.DownloadStatusError{StatusCode: http.StatusGone})
+		mockClient.On("ResolveDownloadURL", download.FileURL, "").Return(&client.DownloadLink{URL: newURL, TTL: 5 * time.Minute}, nil)
+		mockClient.On("DownloadFile", newURL, int64(0)).Return(
+			&MockReadCloser{reader: strings.NewReader("data")}, int64(4), http.StatusOK, nil,
+		)
+
+		worker := NewDownloadWorker(ctx, download, mockRepo, mockClient, mockSSE)
+
+		err := worker.stepDownload()
+		require.NoError(t, err)
+		assert.Equal(t, newURL, *download.DownloadURL)
+		assert.Equal(t, int64(4), download.DownloadedBytes)
+		mockClient.AssertNumberOfCalls(t, "ResolveDownloadURL", 1)
+	})
+
+	t.Run("renew before reconnecting with expired timestamp", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockClient := new(MockProvider)
+		mockSSE := new(MockSSEManager)
+
+		oldURL := "https://download.1fichier.com/old"
+		newURL := "https://download.1fichier.com/new"
+		expiresAt := time.Now().Add(-time.Minute)
+
+		download := &model.Download{
+			ID:                   "test-id",
+			FileURL:              "https://1fichier.com/?test",
+			FileName:             "test.txt",
+			DownloadURL:          &oldURL,
+			DownloadURLExpiresAt: &expiresAt,
+			Type:                 model.TypeMovie,
+		}
+
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+		mockClient.On("ResolveDownloadURL", download.FileURL, "").Return(&client.DownloadLink{URL: newURL, TTL: 5 * time.Minute}, nil)
+		mockClient.On("DownloadFile", newURL, int64(0)).Return(
+			&MockReadCloser{reader: strings.NewReader("data")}, int64(4), http.StatusOK, nil,
+		)
+
+		worker := NewDownloadWorker(ctx, download, mockRepo, mockClient, mockSSE)
+
+		err := worker.stepDownload()
+		require.NoError(t, err)
+		assert.True(t, download.DownloadURLExpiresAt.After(time.Now()))
+		mockClient.AssertNotCalled(t, "DownloadFile", oldURL, int64(0))
+	})
+
+	t.Run("dead link gives up", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDownloadRepository)
+		mockClient := new(MockProvider)
+		mockSSE := new(MockSSEManager)
+
+		deadURL := "https://download.1fichier.com/dead"
+
+		download := &model.Download{
+			ID:          "test-id",
+			FileURL:     "https://1fichier.com/?test",
+			FileName:    "test.txt",
+			DownloadURL: &deadURL,
+			Type:        model.TypeMovie,
+		}
+
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+		mockClient.On("ResolveDownloadURL", download.FileURL, "").Return(&client.DownloadLink{URL: deadURL, TTL: 5 * time.Minute}, nil)
+		mockClient.On("DownloadFile", deadURL, int64(0)).Return(nil, int64(0), 0, &client.DownloadStatusError{StatusCode: http.StatusForbidden})
+
+		worker := NewDownloadWorker(ctx, download, mockRepo, mockClient, mockSSE)
+
+		err := worker.stepDownload()
+		assert.ErrorIs(t, err, client.ErrLinkExpired)
+		mockClient.AssertNumberOfCalls(t, "ResolveDownloadURL", maxDownloadURLRenewals)
+	})
+}
+
 func TestDownloadWorker_UpdateSpeed(t *testing.T) {
 	setupTestConfig(t)
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	mockRepo.On("Update", mock.Anything).Return(nil)
@@ -887,22 +1728,59 @@ func TestDownloadWorker_UpdateSpeed(t *testing.T) {
 	assert.Greater(t, *download.Speed, float64(0))
 }
 
+func TestDownloadWorker_UpdateSpeedSmoothing(t *testing.T) {
+	setupTestConfig(t)
+
+	mockRepo := new(MockDownloadRepository)
+	mockSSE := new(MockSSEManager)
+	mockRepo.On("Update", mock.Anything).Return(nil)
+	mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+	size := int64(10_000)
+	speed := float64(100)
+	download := &model.Download{
+		ID:              "test-id",
+		FileSize:        &size,
+		DownloadedBytes: 1000,
+		Speed:           &speed,
+		Type:            model.TypeMovie,
+	}
+	worker := NewDownloadWorker(context.Background(), download, mockRepo, new(MockProvider), mockSSE)
+
+	// A burst of 900 B/s over one second only moves the average part of the way
+	lastUpdate := time.Now().Add(-1 * time.Second)
+	lastBytes := int64(100)
+	worker.updateSpeed(&lastUpdate, &lastBytes)
+
+	require.NotNil(t, download.Speed)
+	assert.Greater(t, *download.Speed, float64(100))
+	assert.Less(t, *download.Speed, float64(500))
+
+	require.NotNil(t, download.ETA)
+	expected := int64(math.Ceil(float64(size-download.DownloadedBytes) / *download.Speed))
+	assert.Equal(t, expected, *download.ETA)
+	assert.Equal(t, download.ETA, download.ProgressEvent().ETA)
+}
+
 func TestDownloadWorker_Complete(t *testing.T) {
 	setupTestConfig(t)
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	mockRepo.On("Update", mock.Anything).Return(nil)
 	mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
 
+	startedAt := time.Now().Add(-2 * time.Second)
 	download := &model.Download{
-		ID:       "test-id",
-		FileName: "test.txt",
-		Status:   model.StatusDownloading,
-		Type:     model.TypeMovie,
+		ID:              "test-id",
+		FileName:        "test.txt",
+		Status:          model.StatusDownloading,
+		Type:            model.TypeMovie,
+		DownloadedBytes: 12,
+		StartedAt:       &startedAt,
 	}
 
 	// Create the temp file
@@ -918,6 +1796,9 @@ func TestDownloadWorker_Complete(t *testing.T) {
 	assert.Equal(t, model.StatusCompleted, download.Status)
 	assert.Equal(t, float64(100), download.Progress)
 	assert.NotNil(t, download.CompletedAt)
+	require.NotNil(t, download.AverageSpeed)
+	assert.InDelta(t, 6, *download.AverageSpeed, 0.5)
+	assert.Nil(t, download.ETA)
 
 	// Verify that the final file exists
 	finalPath, _ := download.FinalFilePath()
@@ -929,12 +1810,120 @@ func TestDownloadWorker_Complete(t *testing.T) {
 	assert.True(t, os.IsNotExist(err))
 }
 
+func TestDownloadWorker_CompleteExistingFile(t *testing.T) {
+	overwrite := model.ConflictOverwrite
+	tests := []struct {
+		name          string
+		onConflict    *model.ConflictPolicy // Policy of the download
+		defaultPolicy model.ConflictPolicy  // Policy of the settings
+		reserved      []string              // File names of other unfinished downloads
+		wantStatus    model.DownloadStatus
+		wantOutcome   model.ConflictOutcome
+		wantName      string
+		wantContent   string // Content of test.txt after completion
+		wantTemp      bool
+	}{
+		{
+			name:          "fail by default",
+			defaultPolicy: model.ConflictFail,
+			wantStatus:    model.StatusFailed,
+			wantOutcome:   model.OutcomeFailed,
+			wantName:      "test.txt",
+			wantContent:   "existing content",
+			wantTemp:      true,
+		},
+		{
+			name:          "keep both",
+			defaultPolicy: model.ConflictRename,
+			wantStatus:    model.StatusCompleted,
+			wantOutcome:   model.OutcomeRenamed,
+			wantName:      "test (1).txt",
+			wantContent:   "existing content",
+		},
+		{
+			name:          "keep both, next name used by another download",
+			defaultPolicy: model.ConflictRename,
+			reserved:      []string{"test (1).txt"},
+			wantStatus:    model.StatusCompleted,
+			wantOutcome:   model.OutcomeRenamed,
+			wantName:      "test (2).txt",
+			wantContent:   "existing content",
+		},
+		{
+			name:          "skip",
+			defaultPolicy: model.ConflictSkip,
+			wantStatus:    model.StatusCompleted,
+			wantOutcome:   model.OutcomeSkipped,
+			wantName:      "test.txt",
+			wantContent:   "existing content",
+		},
+		{
+			name:          "download policy overrides the default",
+			onConflict:    &overwrite,
+			defaultPolicy: model.ConflictFail,
+			wantStatus:    model.StatusCompleted,
+			wantOutcome:   model.OutcomeOverwritten,
+			wantName:      "test.txt",
+			wantContent:   "test content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t)
+
+			mockRepo := new(MockDownloadRepository)
+			mockSSE := new(MockSSEManager)
+			mockRepo.On("Update", mock.Anything).Return(nil)
+			mockSSE.On("SendEvent", "progress", mock.Anything).Return(nil)
+
+			download := &model.Download{
+				ID:         "test-id",
+				FileName:   "test.txt",
+				Status:     model.StatusDownloading,
+				Type:       model.TypeMovie,
+				OnConflict: tt.onConflict,
+			}
+
+			tempPath, _ := download.TempFilePath()
+			existingPath, _ := download.FinalFilePath()
+			os.MkdirAll(filepath.Dir(tempPath), 0755)
+			os.WriteFile(tempPath, []byte("test content"), 0644)
+			os.WriteFile(existingPath, []byte("existing content"), 0644)
+
+			worker := NewDownloadWorker(context.Background(), download, mockRepo, new(MockProvider), mockSSE)
+			worker.conflictPolicy = tt.defaultPolicy
+			worker.reservedPaths = func(string) (map[string]bool, error) {
+				reserved := make(map[string]bool)
+				for _, name := range tt.reserved {
+					reserved[filepath.Join(filepath.Dir(existingPath), name)] = true
+				}
+				return reserved, nil
+			}
+			worker.complete()
+
+			assert.Equal(t, tt.wantStatus, download.Status)
+			require.NotNil(t, download.ConflictOutcome)
+			assert.Equal(t, tt.wantOutcome, *download.ConflictOutcome)
+
+			finalPath, _ := download.FinalFilePath()
+			assert.Equal(t, tt.wantName, filepath.Base(finalPath))
+			content, err := os.ReadFile(existingPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(content))
+
+			_, err = os.Stat(tempPath)
+			assert.Equal(t, tt.wantTemp, err == nil)
+		})
+	}
+}
+
 func TestDownloadWorker_Fail(t *testing.T) {
 	setupTestConfig(t)
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	mockRepo.On("Update", mock.Anything).Return(nil)
@@ -956,7 +1945,7 @@ func TestDownloadWorker_Fail(t *testing.T) {
 	assert.Equal(t, model.StatusFailed, download.Status)
 	assert.NotNil(t, download.ErrorMessage)
 	assert.Equal(t, "test error", *download.ErrorMessage)
-	assert.Equal(t, 1, download.RetryCount)
+	assert.Equal(t, 0, download.RetryCount) // Only retries are counted
 }
 
 func TestDownloadWorker_CancelCleanup(t *testing.T) {
@@ -964,7 +1953,7 @@ func TestDownloadWorker_CancelCleanup(t *testing.T) {
 
 	ctx := context.Background()
 	mockRepo := new(MockDownloadRepository)
-	mockClient := new(MockOneFichierClient)
+	mockClient := new(MockProvider)
 	mockSSE := new(MockSSEManager)
 
 	mockRepo.On("Update", mock.Anything).Return(nil)